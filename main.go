@@ -2,34 +2,302 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
 	"log"
+	"log/slog"
 	"os"
 	"os/signal"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/lukamindo/rico_parser_go/rico"
 )
 
-const (
-	interval = 1 * time.Minute // Check rate every 1 minute
+// Build information, set at build time with
+//
+//	go build -ldflags "-X main.version=v1.2.3 -X main.commit=$(git rev-parse HEAD) -X main.date=$(date -u +%FT%TZ)"
+//
+// commit and date fall back to the VCS information Go embeds in the binary.
+var (
+	version = "dev"
+	commit  string
+	date    string
+)
 
+// once makes the program fetch the rates a single time, print them and exit.
+// jsonOut switches that output to one JSON object per line for piping.
+var (
+	once    = flag.Bool("once", false, "fetch and print the current rates once, then exit without notifying")
+	jsonOut = flag.Bool("json", false, "with -once, print each rate as a JSON object instead of plain text")
+	showVer = flag.Bool("version", false, "print the version and build information, then exit")
 )
 
+// exitTokenRevoked is the exit code when EXIT_ON_REVOKED_TOKEN is set and Telegram keeps
+// rejecting the bot token, so a supervisor can restart with a fresh configuration.
+const exitTokenRevoked = 3
+
+// buildInfo describes the running build, e.g. "v1.2.3 (commit 0a1b2c3, built 2026-10-14T12:00:00Z)".
+func buildInfo() string {
+	rev, at := commit, date
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range info.Settings {
+			switch {
+			case s.Key == "vcs.revision" && rev == "":
+				rev = s.Value
+			case s.Key == "vcs.time" && at == "":
+				at = s.Value
+			}
+		}
+	}
+	if rev == "" {
+		rev = "unknown"
+	}
+	if at == "" {
+		at = "unknown"
+	}
+	return fmt.Sprintf("%s (commit %s, built %s)", version, rev, at)
+}
+
+// loadConfig reads the JSON or YAML file given with -config, or otherwise merges command line
+// flags with their environment variable fallbacks. Flags take precedence over the environment.
+func loadConfig() (rico.Config, error) {
+	interval := rico.DefaultInterval
+	if v := os.Getenv("CHECK_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return rico.Config{}, fmt.Errorf("invalid CHECK_INTERVAL %q: %w", v, err)
+		}
+		interval = d
+	}
+
+	configPath := flag.String("config", os.Getenv("RICO_CONFIG"), "JSON or YAML config file, used instead of the flags below (env RICO_CONFIG)")
+	// the token falls back to the environment after parsing so -h doesn't print it
+	token := flag.String("token", "", "Telegram bot token (env TELEGRAM_BOT_TOKEN)")
+	channels := flag.String("channel", os.Getenv("TELEGRAM_CHANNEL_ID"), "comma-separated Telegram channel IDs (env TELEGRAM_CHANNEL_ID)")
+	flag.DurationVar(&interval, "interval", interval, "polling interval (env CHECK_INTERVAL)")
+	currencies := flag.String("currencies", envOr("RICO_CURRENCIES", envOr("RICO_CURRENCY", "USD")), "comma-separated currency codes to watch, e.g. USD,EUR,GBP (env RICO_CURRENCIES)")
+	currency := flag.String("currency", "", "deprecated alias of -currencies (env RICO_CURRENCY)")
+	pageURL := flag.String("url", os.Getenv("RICO_URL"), "page to scrape rates from (env RICO_URL)")
+	branch := flag.String("branch", os.Getenv("RICO_BRANCH"), "rico.ge branch to scrape the rates of, e.g. batumi (env RICO_BRANCH)")
+	proxy := flag.String("proxy", os.Getenv("RICO_PROXY_URL"), "proxy for all requests, overriding HTTP_PROXY and HTTPS_PROXY (env RICO_PROXY_URL)")
+	webhook := flag.String("webhook", os.Getenv("WEBHOOK_URL"), "URL to POST rate changes to as JSON (env WEBHOOK_URL)")
+	discord := flag.String("discord", os.Getenv("DISCORD_WEBHOOK_URL"), "Discord webhook URL (env DISCORD_WEBHOOK_URL)")
+	slack := flag.String("slack", os.Getenv("SLACK_WEBHOOK_URL"), "Slack Incoming Webhook URL (env SLACK_WEBHOOK_URL)")
+	tz := flag.String("timezone", os.Getenv("RICO_TIMEZONE"), "time zone for message timestamps, e.g. Europe/Berlin (env RICO_TIMEZONE)")
+	tf := flag.String("time-format", os.Getenv("RICO_TIME_FORMAT"), "layout of message timestamps, e.g. \"02.01.2006 15:04\" (env RICO_TIME_FORMAT)")
+	quiet := flag.String("quiet-hours", os.Getenv("QUIET_HOURS"), "hold back rate change messages and alerts in this daily window, e.g. 22:00-07:00 (env QUIET_HOURS)")
+	inverted := flag.Bool("invert", os.Getenv("RICO_INVERT") == "true", "show rates as currency per 1 GEL (env RICO_INVERT)")
+	flag.Parse()
+
+	if *showVer {
+		fmt.Println("rico_parser_go", buildInfo())
+		os.Exit(0)
+	}
+	if *configPath != "" {
+		return rico.LoadConfig(*configPath)
+	}
+
+	if *token == "" {
+		*token = os.Getenv("TELEGRAM_BOT_TOKEN")
+	}
+	if *currency != "" {
+		*currencies = *currency
+	}
+	cfg := rico.Config{
+		BotToken:        *token,
+		BackupBotTokens: splitList(os.Getenv("TELEGRAM_BACKUP_BOT_TOKENS")),
+		Channels:        splitList(*channels),
+		Interval:        rico.Duration(interval),
+		Currencies:      splitList(*currencies),
+		URL:             *pageURL,
+		Branch:          *branch,
+		ProxyURL:        *proxy,
+		Timezone:        *tz,
+		TimeFormat:      *tf,
+		ParseMode:       rico.ParseMode(os.Getenv("TELEGRAM_PARSE_MODE")),
+		Inverted:        *inverted,
+		SourceLink:      os.Getenv("SOURCE_LINK") == "true",
+		Webhooks:        splitList(*webhook),
+		DiscordWebhooks: splitList(*discord),
+		SlackWebhooks:   splitList(*slack),
+		SkipWeekdays:    splitList(os.Getenv("SKIP_WEEKDAYS")),
+		Holidays:        splitList(os.Getenv("HOLIDAYS")),
+	}
+
+	if *quiet != "" {
+		start, end, ok := strings.Cut(*quiet, "-")
+		if !ok {
+			return rico.Config{}, fmt.Errorf("invalid quiet hours %q: expected start-end, e.g. 22:00-07:00", *quiet)
+		}
+		cfg.QuietHours = &rico.QuietHours{
+			Start:   strings.TrimSpace(start),
+			End:     strings.TrimSpace(end),
+			CatchUp: os.Getenv("QUIET_HOURS_CATCH_UP") == "true",
+		}
+	}
+
+	if v := os.Getenv("BIG_MOVE_PERCENT"); v != "" {
+		pct, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return rico.Config{}, fmt.Errorf("invalid BIG_MOVE_PERCENT %q: %w", v, err)
+		}
+		cfg.BigMove = &rico.BigMove{
+			Percent:    pct,
+			ChannelIDs: splitList(os.Getenv("BIG_MOVE_CHANNEL_ID")),
+			Mention:    os.Getenv("BIG_MOVE_MENTION"),
+		}
+	}
+
+	if host := os.Getenv("SMTP_HOST"); host != "" {
+		port, err := strconv.Atoi(envOr("SMTP_PORT", "587"))
+		if err != nil {
+			return rico.Config{}, fmt.Errorf("invalid SMTP_PORT: %w", err)
+		}
+		cfg.Email = &rico.EmailConfig{
+			Host:     host,
+			Port:     port,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("SMTP_FROM"),
+			To:       splitList(os.Getenv("SMTP_TO")),
+			StartTLS: os.Getenv("SMTP_STARTTLS") != "false",
+		}
+	}
+	if *once {
+		// Telegram and the other notifiers aren't used for a single fetch
+		return cfg, cfg.ValidateCurrencies()
+	}
+	return cfg, cfg.Validate()
+}
+
+// envOr returns the value of the environment variable key, or def when it's unset.
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
 func main() {
+	cfg, err := loadConfig()
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v (see -h)\n", err)
+	}
 
-	botToken := os.Getenv("TELEGRAM_BOT_TOKEN")
-	channelID := os.Getenv("TELEGRAM_CHANNEL_ID")
+	var opts []rico.Option
+	if v := os.Getenv("TELEGRAM_MESSAGE_TEMPLATE"); v != "" {
+		opts = append(opts, rico.WithMessageTemplate(v))
+	}
+	if v := os.Getenv("NUMBER_DECIMALS"); v != "" || os.Getenv("DECIMAL_SEPARATOR") != "" {
+		decimals := 4
+		if v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				log.Fatalf("Invalid NUMBER_DECIMALS %q: %v\n", v, err)
+			}
+			decimals = n
+		}
+		opts = append(opts, rico.WithNumberFormat(decimals, os.Getenv("DECIMAL_SEPARATOR")))
+	}
+	if addr := os.Getenv("HEALTH_ADDR"); addr != "" {
+		opts = append(opts, rico.WithHealthServer(addr, 0))
+	}
+	if os.Getenv("LOG_FORMAT") == "json" {
+		opts = append(opts, rico.WithLogger(slog.New(slog.NewJSONHandler(os.Stderr, nil))))
+	}
+	if os.Getenv("DRY_RUN") == "true" {
+		opts = append(opts, rico.WithDryRun(true))
+	}
+	if os.Getenv("TELEGRAM_COMMANDS") == "true" {
+		opts = append(opts, rico.WithTelegramCommands(true))
+	}
+	if os.Getenv("BATCH_MESSAGES") == "true" {
+		opts = append(opts, rico.WithBatchedMessages(true))
+	}
+	if os.Getenv("STARTUP_MESSAGE") == "true" {
+		opts = append(opts, rico.WithStartupMessage(true))
+	}
+	if os.Getenv("DAILY_SUMMARY") == "true" {
+		opts = append(opts, rico.WithDailySummary(true))
+	}
+	if v := os.Getenv("HEARTBEAT_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Fatalf("Invalid HEARTBEAT_INTERVAL %q: %v\n", v, err)
+		}
+		opts = append(opts, rico.WithHeartbeat(d))
+	}
+	if v := os.Getenv("RETRY_BUDGET"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Fatalf("Invalid RETRY_BUDGET %q: %v\n", v, err)
+		}
+		opts = append(opts, rico.WithRetryBudget(d))
+	}
+	if v := os.Getenv("RICO_JITTER"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			log.Fatalf("Invalid RICO_JITTER %q: %v\n", v, err)
+		}
+		opts = append(opts, rico.WithJitter(f))
+	}
+	if v := os.Getenv("PARSE_DUMP_BYTES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			log.Fatalf("Invalid PARSE_DUMP_BYTES %q: %v\n", v, err)
+		}
+		opts = append(opts, rico.WithParseFailureDump(n, os.Getenv("PARSE_DUMP_DIR")))
+	}
+	if os.Getenv("METRICS_ENABLED") == "true" {
+		opts = append(opts, rico.WithMetrics())
+	}
+	if path := os.Getenv("RICO_STATE_FILE"); path != "" {
+		opts = append(opts, rico.WithStateFile(path))
+	}
+	var store *rico.SQLiteStore
+	if path := os.Getenv("RICO_DB_PATH"); path != "" {
+		var err error
+		if store, err = rico.NewSQLiteStore(path); err != nil {
+			log.Fatalf("Failed to open rate store: %v\n", err)
+		}
+		// closed by rc.Close once Run returns
+		opts = append(opts, rico.WithStore(store))
+	}
 
-	if botToken == "" || channelID == "" {
-		log.Fatal("TELEGRAM_BOT_TOKEN and TELEGRAM_CHANNEL_ID must be set in the environment")
+	if *once {
+		err := printOnce(cfg, opts)
+		// there's no RateChecker to close it
+		if store != nil {
+			if err := store.Close(); err != nil {
+				log.Printf("Failed to close rate store: %v\n", err)
+			}
+		}
+		if err != nil {
+			log.Fatalf("Failed to fetch rates: %v\n", err)
+		}
+		return
 	}
 
-	rc, err := rico.NewRateChecker(botToken, channelID)
+	rc, err := rico.NewRateCheckerFromConfig(cfg, opts...)
 	if err != nil {
 		log.Fatalf("Failed to create RateChecker: %v\n", err)
 	}
 
+	log.Printf("Starting rico_parser_go %s\n", buildInfo())
+	if os.Getenv("VALIDATE_ON_START") == "true" {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		err := rc.Validate(ctx)
+		cancel()
+		if err != nil {
+			log.Fatalf("Startup check failed: %v\n", err)
+		}
+	}
 	ctx, cancel := context.WithCancel(context.Background())
 
 	// Graceful shutdown handling
@@ -41,19 +309,68 @@ func main() {
 		cancel()
 	}()
 
-	// Immediate check on startup
-	rc.CheckForRateChange(ctx)
+	exitOnRevoked := os.Getenv("EXIT_ON_REVOKED_TOKEN") == "true"
+	if exitOnRevoked {
+		go func() {
+			select {
+			case <-rc.TokenRevoked():
+				log.Printf("Telegram rejects the bot token, shutting down...\n")
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+	}
+
+	rc.Run(ctx)
+	if err := rc.Close(); err != nil {
+		log.Printf("Failed to release resources: %v\n", err)
+	}
+
+	select {
+	case <-rc.TokenRevoked():
+		if exitOnRevoked {
+			os.Exit(exitTokenRevoked)
+		}
+	default:
+	}
+}
+
+// printOnce fetches the configured currencies once and prints one line per rate to stdout,
+// either as plain text or as JSON.
+func printOnce(cfg rico.Config, opts []rico.Option) error {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	rates, err := rico.FetchRates(ctx, cfg.Currencies, append([]rico.Option{rico.WithURL(cfg.URL), rico.WithBranch(cfg.Branch), rico.WithProxyURL(cfg.ProxyURL)}, opts...)...)
+	if err != nil {
+		return err
+	}
+	currencies := make([]string, 0, len(rates))
+	for c := range rates {
+		currencies = append(currencies, c)
+	}
+	sort.Strings(currencies)
 
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
+	enc := json.NewEncoder(os.Stdout)
+	for _, c := range currencies {
+		if *jsonOut {
+			if err := enc.Encode(rates[c]); err != nil {
+				return err
+			}
+			continue
+		}
+		fmt.Printf("%s buy %.4f sell %.4f\n", c, rates[c].Buy, rates[c].Sell)
+	}
+	return nil
+}
 
-	for {
-		select {
-		case <-ctx.Done():
-			log.Println("Context canceled, shutting down.")
-			return
-		case <-ticker.C:
-			rc.CheckForRateChange(ctx)
+// splitList splits a comma-separated list, dropping blank entries.
+func splitList(s string) []string {
+	var ret []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			ret = append(ret, v)
 		}
 	}
+	return ret
 }
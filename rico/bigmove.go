@@ -0,0 +1,61 @@
+package rico
+
+import (
+	"context"
+	"fmt"
+	"math"
+)
+
+// BigMove configures a louder alert for a rate that jumps by at least Percent between two
+// checks. It's sent in addition to the normal change message, which still follows the
+// change thresholds.
+type BigMove struct {
+	Percent float64 `json:"percent" yaml:"percent"`
+	// ChannelIDs are the Telegram chats the alert goes to instead of the normal channels,
+	// empty sends it wherever rate changes go.
+	ChannelIDs []string `json:"channel_ids" yaml:"channel_ids"`
+	// Mention is put in front of the alert to get it noticed, e.g. "@here" or "@username".
+	Mention string `json:"mention" yaml:"mention"`
+}
+
+// validate checks the threshold and the chat IDs.
+func (b BigMove) validate() error {
+	if b.Percent <= 0 {
+		return fmt.Errorf("big move percent must be positive, got %g", b.Percent)
+	}
+	for _, id := range b.ChannelIDs {
+		if !channelIDPattern.MatchString(id) {
+			return fmt.Errorf("%w: big move chat %q is neither a numeric ID nor an @channelname", ErrInvalidChannelID, id)
+		}
+	}
+	return nil
+}
+
+// checkBigMove sends a big move alert when buy or sell moved from the rate of the previous
+// check, prev, by at least the configured percentage.
+func (rc *RateChecker) checkBigMove(ctx context.Context, prev *Rate, rate Rate) error {
+	if rc.bigMove == nil || prev == nil {
+		return nil
+	}
+
+	buy, sell := percentMove(prev.Buy, rate.Buy), percentMove(prev.Sell, rate.Sell)
+	if math.Abs(buy) < rc.bigMove.Percent && math.Abs(sell) < rc.bigMove.Percent {
+		return nil
+	}
+
+	f := rc.numbers()
+	text := fmt.Sprintf("🚨 %s moved sharply: buy %s → %s (%s), sell %s → %s (%s)", rate.Currency,
+		f.number(prev.Buy), f.number(rate.Buy), f.percent(buy), f.number(prev.Sell), f.number(rate.Sell), f.percent(sell))
+	if rc.bigMove.Mention != "" {
+		text = rc.bigMove.Mention + " " + text
+	}
+	return rc.notifyUnlessQuiet(ctx, Event{Kind: EventBigMove, Rate: rate, Previous: prev, Text: text, Time: rate.Updated})
+}
+
+// percentMove returns the move from old to new in percent of old, zero when old is zero.
+func percentMove(old, new float64) float64 {
+	if old == 0 {
+		return 0
+	}
+	return (new - old) / old * 100
+}
@@ -0,0 +1,16 @@
+package rico
+
+import "time"
+
+// Clock tells the current time. RateChecker reads the time through it, so tests can
+// control it; see WithClock.
+type Clock interface {
+	Now() time.Time
+}
+
+// realClock is the Clock of the wall clock.
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
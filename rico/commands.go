@@ -0,0 +1,272 @@
+package rico
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	commandPollTimeout = 30 * time.Second // how long a getUpdates call is held open
+	commandRetryDelay  = 5 * time.Second  // wait after a failed getUpdates call
+)
+
+// telegramChat is the chat a Telegram message was posted in.
+type telegramChat struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+}
+
+// telegramMessage is the part of a Telegram message the command handler looks at.
+type telegramMessage struct {
+	Text string       `json:"text"`
+	Chat telegramChat `json:"chat"`
+}
+
+// telegramUpdate is one entry of a getUpdates response. Messages posted in channels
+// arrive as channel_post rather than message.
+type telegramUpdate struct {
+	UpdateID    int64            `json:"update_id"`
+	Message     *telegramMessage `json:"message"`
+	ChannelPost *telegramMessage `json:"channel_post"`
+}
+
+type telegramUpdatesResponse struct {
+	telegramResponse
+	Result []telegramUpdate `json:"result"`
+}
+
+// getUpdates polls Telegram for updates starting at offset, waiting up to poll for one
+// to arrive. A negative offset returns the last -offset updates and drops the others.
+func (t *telegramNotifier) getUpdates(ctx context.Context, offset int64, poll time.Duration) ([]telegramUpdate, error) {
+	if t.disabled() {
+		return nil, ErrTelegramUnauthorized
+	}
+	ctx, cancel := context.WithTimeout(ctx, poll+t.timeout)
+	defer cancel()
+	token := t.current.Load()
+
+	form := url.Values{}
+	form.Set("offset", strconv.FormatInt(offset, 10))
+	form.Set("timeout", strconv.Itoa(int(poll.Seconds())))
+	form.Set("allowed_updates", `["message","channel_post"]`)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint(t.botTokens[token], "getUpdates"), strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("creating getUpdates request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("getting updates: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body telegramUpdatesResponse
+	decodeErr := json.NewDecoder(resp.Body).Decode(&body)
+	if err := t.checkAuth(resp.StatusCode, body.Description); err != nil {
+		t.failover(token, err)
+		return nil, err
+	}
+	if decodeErr != nil {
+		return nil, fmt.Errorf("decoding updates: %w", decodeErr)
+	}
+	t.authFailures.Store(0)
+	if !body.OK {
+		return nil, fmt.Errorf("telegram refused getUpdates (status %d): %s", resp.StatusCode, body.Description)
+	}
+	return body.Result, nil
+}
+
+// chatID returns the ID chat is known by: the configured channel ID (which may be an
+// @channelname) when chat is one of the channels messages are sent to, or its numeric ID.
+func (t *telegramNotifier) chatID(chat telegramChat) string {
+	numeric := strconv.FormatInt(chat.ID, 10)
+	for _, id := range t.channelIDs {
+		if id == numeric || (chat.Username != "" && strings.EqualFold(id, "@"+chat.Username)) {
+			return id
+		}
+	}
+	return numeric
+}
+
+// startCommands starts answering Telegram commands when enabled. The returned function
+// stops the handler and waits for it to return.
+func (rc *RateChecker) startCommands(ctx context.Context) (stop func()) {
+	if !rc.commands {
+		return func() {}
+	}
+
+	// loaded before the first check, so it already goes out to the subscribers
+	if err := rc.loadSubscriptions(ctx); err != nil {
+		rc.logger.Error("reading telegram subscriptions", "error", err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		rc.pollCommands(ctx)
+	}()
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+// pollCommands reads updates from Telegram until ctx is canceled and answers the
+// commands posted in any chat the bot can read.
+func (rc *RateChecker) pollCommands(ctx context.Context) {
+	rc.logger.Info("answering telegram commands")
+
+	var offset int64
+	// commands sent while the bot wasn't polling would be answered late, possibly by hours
+	skipPending := true
+	token := rc.telegram.current.Load()
+	for {
+		if t := rc.telegram.current.Load(); t != token {
+			// update IDs are per bot, so another token starts counting afresh
+			token, offset, skipPending = t, 0, true
+		}
+		var (
+			updates []telegramUpdate
+			err     error
+		)
+		if skipPending {
+			if offset, err = rc.pendingOffset(ctx); err == nil {
+				skipPending = false
+			}
+		} else {
+			updates, err = rc.telegram.getUpdates(ctx, offset, commandPollTimeout)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			rc.logger.Error("reading telegram commands", "error", err)
+			if rc.telegram.disabled() {
+				return
+			}
+			if sleep(ctx, commandRetryDelay) != nil {
+				return
+			}
+			continue
+		}
+
+		for _, u := range updates {
+			offset = u.UpdateID + 1
+			msg := u.Message
+			if msg == nil {
+				msg = u.ChannelPost
+			}
+			if msg == nil {
+				continue
+			}
+			if err := rc.handleCommand(ctx, msg); err != nil {
+				rc.logger.Error("answering telegram command", "chat", msg.Chat.ID, "text", msg.Text, "error", err)
+			}
+		}
+	}
+}
+
+// pendingOffset returns the offset right after the last update queued up for the bot,
+// dropping the queue, or 0 when it's empty.
+func (rc *RateChecker) pendingOffset(ctx context.Context) (int64, error) {
+	updates, err := rc.telegram.getUpdates(ctx, -1, 0)
+	if err != nil || len(updates) == 0 {
+		return 0, err
+	}
+	rc.logger.Info("skipping telegram commands sent before polling started", "update_id", updates[len(updates)-1].UpdateID)
+	return updates[len(updates)-1].UpdateID + 1, nil
+}
+
+// handleCommand answers a single message if it's a known command. Other messages are
+// ignored.
+func (rc *RateChecker) handleCommand(ctx context.Context, msg *telegramMessage) error {
+	fields := strings.Fields(msg.Text)
+	if len(fields) == 0 {
+		return nil
+	}
+	// in groups commands may be addressed to a bot, as in /rate@somebot
+	command, _, _ := strings.Cut(fields[0], "@")
+
+	chatID := rc.telegram.chatID(msg.Chat)
+	var reply string
+	switch command {
+	case "/rate":
+		var err error
+		if reply, err = rc.rateReply(fields[1:]); err != nil {
+			return err
+		}
+	case "/subscribe":
+		var currencies []string
+		for _, a := range fields[1:] {
+			c := normalizeCurrency(a)
+			if !rc.watches(c) {
+				return rc.reply(ctx, chatID, rc.parseMode.escape(fmt.Sprintf("%s isn't watched", c)))
+			}
+			currencies = append(currencies, c)
+		}
+		subs, err := rc.subscribe(ctx, chatID, currencies)
+		if err != nil {
+			return err
+		}
+		reply = rc.parseMode.escape("subscribed to " + describeSubscription(subs))
+	case "/unsubscribe":
+		if err := rc.unsubscribe(ctx, chatID); err != nil {
+			return err
+		}
+		reply = rc.parseMode.escape("unsubscribed")
+	default:
+		return nil
+	}
+	return rc.reply(ctx, chatID, reply)
+}
+
+// reply sends the answer to a command to chatID, or only logs it in dry run mode.
+func (rc *RateChecker) reply(ctx context.Context, chatID, text string) error {
+	if rc.dryRun {
+		rc.logger.Info("dry run, not sending command reply", "chat", chatID, "text", text)
+		return nil
+	}
+	return rc.telegram.sendToChannel(ctx, chatID, text)
+}
+
+// rateReply renders the last stored rate of the currencies in args, or of every watched
+// currency when args is empty.
+func (rc *RateChecker) rateReply(args []string) (string, error) {
+	currencies := rc.currencies
+	if len(args) > 0 {
+		currencies = nil
+		for _, a := range args {
+			currencies = append(currencies, normalizeCurrency(a))
+		}
+	}
+
+	var parts []string
+	for _, c := range currencies {
+		if !rc.watches(c) {
+			parts = append(parts, rc.parseMode.escape(fmt.Sprintf("%s isn't watched", c)))
+			continue
+		}
+		rate, ok := rc.Rate(c)
+		if !ok {
+			parts = append(parts, rc.parseMode.escape(fmt.Sprintf("no %s rate fetched yet", c)))
+			continue
+		}
+		text, err := rc.formatMessage(rate, nil, rate.Updated)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, text)
+	}
+	return strings.Join(parts, "\n\n"), nil
+}
@@ -0,0 +1,183 @@
+package rico
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeTelegram answers Bot API calls in place of api.telegram.org and records them.
+type fakeTelegram struct {
+	// updates returns what getUpdates answers for offset, nil for nothing.
+	updates func(offset int64) []telegramUpdate
+
+	mu    sync.Mutex
+	calls []url.Values // form of every call, with the method under "method"
+}
+
+func (f *fakeTelegram) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := req.ParseForm(); err != nil {
+		return nil, err
+	}
+	form := req.PostForm
+	form.Set("method", path.Base(req.URL.Path))
+	f.mu.Lock()
+	f.calls = append(f.calls, form)
+	f.mu.Unlock()
+
+	body := `{"ok":true,"result":true}`
+	if form.Get("method") == "getUpdates" {
+		var updates []telegramUpdate
+		if f.updates != nil {
+			offset, _ := strconv.ParseInt(form.Get("offset"), 10, 64)
+			updates = f.updates(offset)
+		}
+		if len(updates) == 0 && form.Get("timeout") != "0" {
+			// stands in for the long poll, so the poller doesn't spin
+			select {
+			case <-req.Context().Done():
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+		b, err := json.Marshal(map[string]any{"ok": true, "result": updates})
+		if err != nil {
+			return nil, err
+		}
+		body = string(b)
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+// called returns the forms of the calls of a Bot API method.
+func (f *fakeTelegram) called(method string) []url.Values {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var ret []url.Values
+	for _, c := range f.calls {
+		if c.Get("method") == method {
+			ret = append(ret, c)
+		}
+	}
+	return ret
+}
+
+// newTelegramChecker returns a checker watching currencies, answering commands and
+// sending to the @rates channel through tg.
+func newTelegramChecker(t *testing.T, tg *fakeTelegram, currencies []string, opts ...Option) *RateChecker {
+	t.Helper()
+	opts = append([]Option{WithHTTPClient(&http.Client{Transport: tg}), WithTelegramCommands(true),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))}, opts...)
+	rc, err := NewRateChecker("123456:ABC-def", []string{"@rates"}, currencies, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rc
+}
+
+func TestCommandsDryRun(t *testing.T) {
+	tg := &fakeTelegram{}
+	rc := newTelegramChecker(t, tg, nil, WithDryRun(true))
+
+	for _, text := range []string{"/rate", "/subscribe XYZ"} {
+		if err := rc.handleCommand(context.Background(), &telegramMessage{Text: text, Chat: telegramChat{ID: 42}}); err != nil {
+			t.Fatalf("%s: %v", text, err)
+		}
+	}
+	if sent := tg.called("sendMessage"); len(sent) > 0 {
+		t.Errorf("dry run sent %d replies, want none: %v", len(sent), sent)
+	}
+}
+
+func TestPollCommandsSkipsPendingUpdates(t *testing.T) {
+	rate := func(id int64) []telegramUpdate {
+		return []telegramUpdate{{UpdateID: id, Message: &telegramMessage{Text: "/rate", Chat: telegramChat{ID: 42}}}}
+	}
+	var once sync.Once
+	tg := &fakeTelegram{updates: func(offset int64) []telegramUpdate {
+		switch offset {
+		case -1:
+			// the last of the commands sent while the bot was down
+			return rate(7)
+		case 8:
+			var ret []telegramUpdate
+			once.Do(func() { ret = rate(8) })
+			return ret
+		}
+		return nil
+	}}
+	rc := newTelegramChecker(t, tg, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		rc.pollCommands(ctx)
+	}()
+	for deadline := time.Now().Add(5 * time.Second); len(tg.called("sendMessage")) == 0 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	polls := tg.called("getUpdates")
+	if len(polls) < 2 || polls[0].Get("offset") != "-1" || polls[0].Get("timeout") != "0" || polls[1].Get("offset") != "8" {
+		t.Errorf("getUpdates calls = %v, want one dropping the queue, then polling from offset 8", polls)
+	}
+	if sent := tg.called("sendMessage"); len(sent) != 1 {
+		t.Errorf("answered %d commands, want only the one sent after startup", len(sent))
+	}
+}
+
+func TestSubscriptionsFilterAlertsAndBatches(t *testing.T) {
+	ctx := context.Background()
+	tg := &fakeTelegram{}
+	rc := newTelegramChecker(t, tg, []string{"USD", "EUR", "GBP"})
+	for chat, currency := range map[string]string{"42": "EUR", "43": "USD"} {
+		if _, err := rc.subscribe(ctx, chat, []string{currency}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := rc.alert(ctx, "USD", "USD rate appears frozen"); err != nil {
+		t.Fatal(err)
+	}
+	var changes []Event
+	for _, c := range []string{"USD", "EUR", "GBP"} {
+		changes = append(changes, Event{Kind: EventRateChange, Rate: Rate{Currency: c, Buy: 2.7, Sell: 2.72}})
+	}
+	now := time.Now()
+	if err := rc.notify(ctx, Event{Kind: EventRateChanges, Changes: changes, Text: rc.formatBatch(changes, now), Time: now}); err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string][]string)
+	for _, m := range tg.called("sendMessage") {
+		got[m.Get("chat_id")] = append(got[m.Get("chat_id")], m.Get("text"))
+	}
+	if n := len(got["@rates"]); n != 2 {
+		t.Errorf("channel got %d messages, want the alert and the whole batch", n)
+	}
+	if len(got["42"]) != 1 {
+		t.Fatalf("EUR subscriber got %q, want only the batch", got["42"])
+	}
+	if batch := got["42"][0]; !strings.Contains(batch, "EUR") || strings.Contains(batch, "USD") || strings.Contains(batch, "GBP") {
+		t.Errorf("EUR subscriber got batch %q, want only the EUR line", batch)
+	}
+	if len(got["43"]) != 2 || !strings.Contains(got["43"][0], "frozen") {
+		t.Errorf("USD subscriber got %q, want the alert and the batch", got["43"])
+	}
+}
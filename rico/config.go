@@ -0,0 +1,255 @@
+package rico
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Duration is a time.Duration that reads and writes as a string like "5m" or "30s".
+type Duration time.Duration
+
+// MarshalJSON implements json.Marshaler.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"5m\": %w", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// MarshalYAML implements yaml.Marshaler.
+func (d Duration) MarshalYAML() (any, error) {
+	return time.Duration(d).String(), nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	var s string
+	if err := node.Decode(&s); err != nil {
+		return fmt.Errorf("duration must be a string like \"5m\": %w", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Config is the file form of a RateChecker's settings.
+type Config struct {
+	BotToken   string   `json:"bot_token" yaml:"bot_token"`
+	Channels   []string `json:"channels" yaml:"channels"`
+	Interval   Duration `json:"interval" yaml:"interval"`
+	Currencies []string `json:"currencies" yaml:"currencies"`
+	URL        string   `json:"url" yaml:"url"`
+	Branch     string   `json:"branch" yaml:"branch"` // rico.ge branch, e.g. "batumi"
+	ProxyURL   string   `json:"proxy_url" yaml:"proxy_url"`
+	Timezone   string   `json:"timezone" yaml:"timezone"`
+	TimeFormat string   `json:"time_format" yaml:"time_format"`
+
+	// tried in order when Telegram rejects or rate limits BotToken
+	BackupBotTokens []string `json:"backup_bot_tokens" yaml:"backup_bot_tokens"`
+
+	MinChange        float64 `json:"min_change" yaml:"min_change"`
+	MinPercentChange float64 `json:"min_percent_change" yaml:"min_percent_change"`
+	SpreadAlert      float64 `json:"spread_alert" yaml:"spread_alert"`
+
+	BigMove *BigMove `json:"big_move" yaml:"big_move"`
+
+	QuietHours   *QuietHours `json:"quiet_hours" yaml:"quiet_hours"`
+	SkipWeekdays []string    `json:"skip_weekdays" yaml:"skip_weekdays"` // e.g. ["Sat", "Sun"]
+	Holidays     []string    `json:"holidays" yaml:"holidays"`           // "2006-01-02" dates
+
+	ParseMode       ParseMode    `json:"parse_mode" yaml:"parse_mode"`
+	Inverted        bool         `json:"inverted" yaml:"inverted"`       // show rates per 1 GEL
+	SourceLink      bool         `json:"source_link" yaml:"source_link"` // end plain messages with the page URL
+	Webhooks        []string     `json:"webhooks" yaml:"webhooks"`
+	DiscordWebhooks []string     `json:"discord_webhooks" yaml:"discord_webhooks"`
+	SlackWebhooks   []string     `json:"slack_webhooks" yaml:"slack_webhooks"`
+	Email           *EmailConfig `json:"email" yaml:"email"`
+}
+
+// LoadConfig reads and validates the config file at path. Files ending in .yaml or .yml
+// are parsed as YAML, anything else as JSON.
+func LoadConfig(path string) (Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("reading config: %w", err)
+	}
+
+	unmarshal := json.Unmarshal
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		unmarshal = yaml.Unmarshal
+	}
+
+	var cfg Config
+	if err := unmarshal(b, &cfg); err != nil {
+		return Config{}, fmt.Errorf("parsing config %s: %w", path, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, fmt.Errorf("config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// ValidateCurrencies checks that Currencies only lists codes rico.ge quotes. Validate
+// checks it too; on its own it suits uses that don't notify, such as a single fetch.
+func (c Config) ValidateCurrencies() error {
+	for _, currency := range c.Currencies {
+		code := normalizeCurrency(currency)
+		if code == "" {
+			return errors.New("currencies must not contain empty codes")
+		}
+		if !knownCurrency(code) {
+			return fmt.Errorf("currencies: rico.ge doesn't list %q", currency)
+		}
+	}
+	return nil
+}
+
+// Validate checks that the required settings are present and the values make sense.
+func (c Config) Validate() error {
+	hasOther := len(c.Webhooks) > 0 || len(c.DiscordWebhooks) > 0 || len(c.SlackWebhooks) > 0 || c.Email != nil
+	// Telegram is optional when events go to another notifier instead
+	if !hasOther || c.BotToken != "" || len(c.Channels) > 0 {
+		if c.BotToken == "" {
+			return errors.New("bot_token is required")
+		}
+		if len(c.Channels) == 0 {
+			return errors.New("channels must list at least one channel")
+		}
+	}
+	if err := c.ValidateCurrencies(); err != nil {
+		return err
+	}
+	if c.Timezone != "" {
+		if _, err := time.LoadLocation(c.Timezone); err != nil {
+			return fmt.Errorf("timezone %q: %w", c.Timezone, err)
+		}
+	}
+	if c.TimeFormat != "" {
+		if err := validateTimeFormat(c.TimeFormat); err != nil {
+			return err
+		}
+	}
+	if c.Interval < 0 {
+		return fmt.Errorf("interval must be positive, got %s", time.Duration(c.Interval))
+	}
+	if c.MinChange < 0 {
+		return errors.New("min_change must not be negative")
+	}
+	if c.MinPercentChange < 0 {
+		return errors.New("min_percent_change must not be negative")
+	}
+	if c.SpreadAlert < 0 {
+		return errors.New("spread_alert must not be negative")
+	}
+	if c.BigMove != nil {
+		if err := c.BigMove.validate(); err != nil {
+			return err
+		}
+	}
+	if c.QuietHours != nil {
+		if _, _, err := c.QuietHours.window(); err != nil {
+			return err
+		}
+	}
+	for _, d := range c.SkipWeekdays {
+		if _, err := ParseWeekday(d); err != nil {
+			return fmt.Errorf("skip_weekdays: %w", err)
+		}
+	}
+	if _, err := parseHolidays(c.Holidays); err != nil {
+		return fmt.Errorf("holidays: %w", err)
+	}
+	if !c.ParseMode.valid() {
+		return fmt.Errorf("parse_mode %q is not supported", c.ParseMode)
+	}
+	if c.Email != nil {
+		if err := c.Email.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// options translates the config into the matching options.
+func (c Config) options() []Option {
+	opts := []Option{
+		WithURL(c.URL),
+		WithBranch(c.Branch),
+		WithProxyURL(c.ProxyURL),
+		WithTimezone(c.Timezone),
+		WithMinChange(c.MinChange),
+		WithMinPercentChange(c.MinPercentChange),
+		WithSpreadAlert(c.SpreadAlert),
+		WithParseMode(c.ParseMode),
+		WithInvertedRates(c.Inverted),
+		WithSourceLink(c.SourceLink),
+	}
+	if c.Interval > 0 {
+		opts = append(opts, WithInterval(time.Duration(c.Interval)))
+	}
+	if c.TimeFormat != "" {
+		opts = append(opts, WithTimeFormat(c.TimeFormat))
+	}
+	for _, u := range c.Webhooks {
+		opts = append(opts, WithWebhook(u))
+	}
+	for _, u := range c.DiscordWebhooks {
+		opts = append(opts, WithDiscordWebhook(u))
+	}
+	for _, u := range c.SlackWebhooks {
+		opts = append(opts, WithSlackWebhook(u))
+	}
+	if c.Email != nil {
+		opts = append(opts, WithEmail(*c.Email))
+	}
+	if c.BigMove != nil {
+		opts = append(opts, WithBigMove(*c.BigMove))
+	}
+	if c.QuietHours != nil {
+		opts = append(opts, WithQuietHours(*c.QuietHours))
+	}
+	for _, d := range c.SkipWeekdays {
+		// checked by Validate
+		if day, err := ParseWeekday(d); err == nil {
+			opts = append(opts, WithSkipWeekdays(day))
+		}
+	}
+	if len(c.Holidays) > 0 {
+		opts = append(opts, WithHolidays(c.Holidays...))
+	}
+	if len(c.BackupBotTokens) > 0 {
+		opts = append(opts, WithBackupBotTokens(c.BackupBotTokens...))
+	}
+	return opts
+}
+
+// NewRateCheckerFromConfig validates cfg and creates a RateChecker from it. opts are
+// applied after the config, so they can tune settings the config doesn't cover.
+func NewRateCheckerFromConfig(cfg Config, opts ...Option) (*RateChecker, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return NewRateChecker(cfg.BotToken, cfg.Channels, cfg.Currencies, append(cfg.options(), opts...)...)
+}
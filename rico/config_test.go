@@ -0,0 +1,22 @@
+package rico
+
+import "testing"
+
+func TestConfigValidateCurrencies(t *testing.T) {
+	tests := []struct {
+		currencies []string
+		ok         bool
+	}{
+		{nil, true},
+		{[]string{"USD", " eur ", "gbp"}, true},
+		{[]string{"USD", "XYZ"}, false},
+		{[]string{"GEL"}, false}, // the lari is what the others are quoted in
+		{[]string{"USD", " "}, false},
+	}
+	for _, tt := range tests {
+		err := Config{Currencies: tt.currencies}.ValidateCurrencies()
+		if (err == nil) != tt.ok {
+			t.Errorf("ValidateCurrencies(%q) = %v, want ok %v", tt.currencies, err, tt.ok)
+		}
+	}
+}
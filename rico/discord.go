@@ -0,0 +1,83 @@
+package rico
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// discordNotifier posts events as embeds to a Discord webhook.
+type discordNotifier struct {
+	client  *http.Client
+	url     string
+	timeout time.Duration
+	numbers numberFormat // how values are written, see WithNumberFormat
+}
+
+type discordField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+type discordEmbed struct {
+	Title       string         `json:"title"`
+	Description string         `json:"description,omitempty"`
+	Fields      []discordField `json:"fields,omitempty"`
+	Timestamp   string         `json:"timestamp"`
+}
+
+type discordMessage struct {
+	Embeds []discordEmbed `json:"embeds"`
+}
+
+// Notify implements Notifier.
+func (d *discordNotifier) Notify(ctx context.Context, ev Event) error {
+	embed := discordEmbed{Timestamp: ev.Time.Format(time.RFC3339)}
+	if ev.Kind == EventRateChange {
+		buyChange, sellChange := ev.changes(d.numbers)
+		embed.Title = fmt.Sprintf("1 %s", ev.Rate.Currency)
+		embed.Fields = []discordField{
+			{Name: "ყიდვა", Value: d.numbers.number(ev.Rate.Buy) + buyChange, Inline: true},
+			{Name: "გაყიდვა", Value: d.numbers.number(ev.Rate.Sell) + sellChange, Inline: true},
+		}
+		if ev.Rate.hasTransfer() {
+			embed.Fields = append(embed.Fields,
+				discordField{Name: "უნაღდო ყიდვა", Value: d.numbers.number(ev.Rate.TransferBuy), Inline: true},
+				discordField{Name: "უნაღდო გაყიდვა", Value: d.numbers.number(ev.Rate.TransferSell), Inline: true},
+			)
+		}
+	} else {
+		embed.Title = "rico.ge"
+		embed.Description = ev.Text
+	}
+
+	body, err := json.Marshal(discordMessage{Embeds: []discordEmbed{embed}})
+	if err != nil {
+		return fmt.Errorf("discord: encoding message: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("discord: creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("discord: sending message: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Discord answers 204 No Content, or 200 when ?wait=true is set on the webhook URL.
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("discord: received unexpected status: %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package rico
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// dumpBody keeps up to the configured number of bytes of a page that failed to parse,
+// so a layout change can be diagnosed from what was actually served. It writes them to a
+// file in the dump directory, or logs them when there's none.
+func (rc *RateChecker) dumpBody(pageURL string, body []byte, parseErr error) {
+	if rc.dumpSize <= 0 {
+		return
+	}
+	truncated := len(body) > rc.dumpSize
+	if truncated {
+		body = body[:rc.dumpSize]
+	}
+
+	if rc.dumpDir == "" {
+		rc.logger.Warn("page failed to parse", "url", pageURL, "error", parseErr, "truncated", truncated, "body", string(body))
+		return
+	}
+	name := filepath.Join(rc.dumpDir, fmt.Sprintf("rico-%s.html", rc.clock.Now().Format("20060102-150405.000")))
+	if err := os.WriteFile(name, body, 0o644); err != nil {
+		rc.logger.Error("writing page dump", "path", name, "error", err)
+		return
+	}
+	rc.logger.Warn("page failed to parse, dumped it", "url", pageURL, "error", parseErr, "truncated", truncated, "path", name)
+}
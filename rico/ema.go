@@ -0,0 +1,59 @@
+package rico
+
+import (
+	"context"
+	"fmt"
+)
+
+// emaState holds the short and long exponential moving averages of a currency's sell
+// price.
+type emaState struct {
+	short, long float64
+	samples     int  // observations folded in so far
+	above       bool // whether the short EMA was above the long one at the last check
+}
+
+// update folds in the sell price v. Each EMA is
+//
+//	EMA_t = α·v + (1-α)·EMA_{t-1}, with α = 2/(N+1)
+//
+// for its period N, seeded with the first observation.
+func (s *emaState) update(v float64, shortPeriod, longPeriod int) {
+	if s.samples == 0 {
+		s.short, s.long = v, v
+	} else {
+		s.short = ema(s.short, v, shortPeriod)
+		s.long = ema(s.long, v, longPeriod)
+	}
+	s.samples++
+}
+
+func ema(prev, v float64, period int) float64 {
+	alpha := 2 / float64(period+1)
+	return alpha*v + (1-alpha)*prev
+}
+
+// checkEMACross updates the EMAs of rate's currency and alerts when the short EMA crosses
+// the long one. Nothing is reported during warm-up, until the long period is filled with
+// observations, because the averages still lean on their seed until then.
+func (rc *RateChecker) checkEMACross(ctx context.Context, rate Rate) error {
+	if rc.emaLong == 0 {
+		return nil
+	}
+
+	s := rc.ema[rate.Currency]
+	s.update(rate.Sell, rc.emaShort, rc.emaLong)
+	wasAbove, warm := s.above, s.samples > rc.emaLong
+	s.above = s.short > s.long
+	rc.ema[rate.Currency] = s
+	if !warm || s.above == wasAbove || s.short == s.long {
+		return nil
+	}
+
+	direction := "below"
+	if s.above {
+		direction = "above"
+	}
+	return rc.alert(ctx, rate.Currency, fmt.Sprintf("📈 %s sell EMA(%d) crossed %s EMA(%d): %s vs %s",
+		rate.Currency, rc.emaShort, direction, rc.emaLong, rc.number(s.short), rc.number(s.long)))
+}
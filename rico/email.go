@@ -0,0 +1,137 @@
+package rico
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EmailConfig configures the SMTP notifier.
+type EmailConfig struct {
+	Host     string   `json:"host" yaml:"host"`
+	Port     int      `json:"port" yaml:"port"` // defaults to 587
+	Username string   `json:"username" yaml:"username"`
+	Password string   `json:"password" yaml:"password"`
+	From     string   `json:"from" yaml:"from"`
+	To       []string `json:"to" yaml:"to"`
+	StartTLS bool     `json:"starttls" yaml:"starttls"` // upgrade the connection with STARTTLS before authenticating
+}
+
+func (c EmailConfig) validate() error {
+	if c.Host == "" {
+		return errors.New("email: host is required")
+	}
+	if c.From == "" {
+		return errors.New("email: from address is required")
+	}
+	if len(c.To) == 0 {
+		return errors.New("email: at least one recipient is required")
+	}
+	return nil
+}
+
+// emailNotifier sends events as plain text emails over SMTP.
+type emailNotifier struct {
+	cfg        EmailConfig
+	timeout    time.Duration
+	location   *time.Location
+	timeFormat string
+	numbers    numberFormat // how values are written, see WithNumberFormat
+}
+
+// Notify implements Notifier.
+func (e *emailNotifier) Notify(ctx context.Context, ev Event) error {
+	at := ev.Time.In(e.location).Format(e.timeFormat)
+
+	var subject, body string
+	if ev.Kind == EventRateChange {
+		buyChange, sellChange := ev.changes(e.numbers)
+		subject = fmt.Sprintf("%s rate: buy %s, sell %s", ev.Rate.Currency, e.numbers.number(ev.Rate.Buy), e.numbers.number(ev.Rate.Sell))
+		body = fmt.Sprintf("%s - 1 %s\r\nყიდვა: %s%s\r\nგაყიდვა: %s%s\r\n",
+			at, ev.Rate.Currency, e.numbers.number(ev.Rate.Buy), buyChange, e.numbers.number(ev.Rate.Sell), sellChange)
+		if ev.Rate.hasTransfer() {
+			body += fmt.Sprintf("უნაღდო ყიდვა: %s\r\nუნაღდო გაყიდვა: %s\r\n", e.numbers.number(ev.Rate.TransferBuy), e.numbers.number(ev.Rate.TransferSell))
+		}
+	} else {
+		subject = "rico.ge alert"
+		body = fmt.Sprintf("%s\r\n%s\r\n", at, strings.ReplaceAll(ev.Text, "\n", "\r\n"))
+	}
+
+	if err := e.send(ctx, subject, body); err != nil {
+		return fmt.Errorf("email: %w", err)
+	}
+	return nil
+}
+
+// send delivers one message to all recipients.
+func (e *emailNotifier) send(ctx context.Context, subject, body string) error {
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+
+	port := e.cfg.Port
+	if port == 0 {
+		port = 587
+	}
+	addr := net.JoinHostPort(e.cfg.Host, strconv.Itoa(port))
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("connecting to %s: %w", addr, err)
+	}
+	// net/smtp isn't context aware, so bound the whole conversation by the deadline
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	c, err := smtp.NewClient(conn, e.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("starting SMTP session: %w", err)
+	}
+	defer c.Close()
+
+	if e.cfg.StartTLS {
+		if err := c.StartTLS(&tls.Config{ServerName: e.cfg.Host}); err != nil {
+			return fmt.Errorf("starting TLS: %w", err)
+		}
+	}
+	if e.cfg.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", e.cfg.Username, e.cfg.Password, e.cfg.Host)); err != nil {
+			return fmt.Errorf("authenticating: %w", err)
+		}
+	}
+
+	if err := c.Mail(e.cfg.From); err != nil {
+		return fmt.Errorf("setting sender: %w", err)
+	}
+	for _, to := range e.cfg.To {
+		if err := c.Rcpt(to); err != nil {
+			return fmt.Errorf("adding recipient %s: %w", to, err)
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("starting message: %w", err)
+	}
+	msg := "From: " + e.cfg.From + "\r\n" +
+		"To: " + strings.Join(e.cfg.To, ", ") + "\r\n" +
+		"Subject: " + subject + "\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"\r\n" + body
+	if _, err := w.Write([]byte(msg)); err != nil {
+		return fmt.Errorf("writing message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("finishing message: %w", err)
+	}
+	return c.Quit()
+}
@@ -0,0 +1,115 @@
+package rico
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Extreme is a value together with the time it was observed.
+type Extreme struct {
+	Value float64
+	Time  time.Time
+}
+
+// Extremes holds the highest and lowest buy and sell values seen for a currency.
+type Extremes struct {
+	Currency string
+	HighBuy  Extreme
+	LowBuy   Extreme
+	HighSell Extreme
+	LowSell  Extreme
+}
+
+// ExtremesStore is a Store that can also report the extremes of the rates it saved,
+// so the all-time highs and lows survive restarts. SQLiteStore implements it.
+type ExtremesStore interface {
+	Store
+	// Extremes returns the extremes of the saved rates of currency; ok is false when
+	// none were saved yet.
+	Extremes(ctx context.Context, currency string) (e Extremes, ok bool, err error)
+}
+
+func newExtremes(rate Rate, at time.Time) Extremes {
+	buy, sell := Extreme{Value: rate.Buy, Time: at}, Extreme{Value: rate.Sell, Time: at}
+	return Extremes{Currency: rate.Currency, HighBuy: buy, LowBuy: buy, HighSell: sell, LowSell: sell}
+}
+
+// merge folds the extremes of o into e. On a tie the earlier observation is kept.
+func (e *Extremes) merge(o Extremes) {
+	higher := func(x, y float64) bool { return x > y }
+	lower := func(x, y float64) bool { return x < y }
+	e.HighBuy = pickExtreme(e.HighBuy, o.HighBuy, higher)
+	e.LowBuy = pickExtreme(e.LowBuy, o.LowBuy, lower)
+	e.HighSell = pickExtreme(e.HighSell, o.HighSell, higher)
+	e.LowSell = pickExtreme(e.LowSell, o.LowSell, lower)
+}
+
+// pickExtreme returns b instead of a when a is unset or b beats it according to better.
+func pickExtreme(a, b Extreme, better func(x, y float64) bool) Extreme {
+	if b.Time.IsZero() {
+		return a
+	}
+	if a.Time.IsZero() || better(b.Value, a.Value) || (b.Value == a.Value && b.Time.Before(a.Time)) {
+		return b
+	}
+	return a
+}
+
+// recordExtremes folds rate observed at the given time into the extremes of its currency.
+func (rc *RateChecker) recordExtremes(rate Rate, at time.Time) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	e, ok := rc.extremes[rate.Currency]
+	if !ok {
+		rc.extremes[rate.Currency] = newExtremes(rate, at)
+		return
+	}
+	e.merge(newExtremes(rate, at))
+	rc.extremes[rate.Currency] = e
+}
+
+// loadExtremes merges the extremes kept by the store into the in-memory ones. It only
+// does work once, on the first check after startup that manages to read them.
+func (rc *RateChecker) loadExtremes(ctx context.Context) error {
+	s, ok := rc.store.(ExtremesStore)
+	if !ok || rc.extremesLoaded {
+		return nil
+	}
+
+	loaded := make(map[string]Extremes)
+	for _, currency := range rc.currencies {
+		e, ok, err := s.Extremes(ctx, currency)
+		if err != nil {
+			return fmt.Errorf("loading %s extremes: %w", currency, err)
+		}
+		if ok {
+			loaded[currency] = e
+		}
+	}
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	for currency, e := range loaded {
+		if cur, ok := rc.extremes[currency]; ok {
+			e.merge(cur)
+		}
+		rc.extremes[currency] = e
+	}
+	rc.extremesLoaded = true
+	return nil
+}
+
+// Extremes returns the highest and lowest rates seen per currency code, since startup or,
+// with a store implementing ExtremesStore, since the store was created.
+func (rc *RateChecker) Extremes() map[string]Extremes {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+
+	ret := make(map[string]Extremes, len(rc.extremes))
+	for c, e := range rc.extremes {
+		ret[c] = e
+	}
+	return ret
+}
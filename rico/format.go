@@ -0,0 +1,322 @@
+package rico
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// ParseMode selects how Telegram renders message text.
+type ParseMode string
+
+const (
+	ParseModePlain      ParseMode = ""
+	ParseModeMarkdownV2 ParseMode = "MarkdownV2"
+	ParseModeHTML       ParseMode = "HTML"
+)
+
+// markdownV2Escaper escapes the characters Telegram reserves in MarkdownV2 text.
+var markdownV2Escaper = strings.NewReplacer(
+	`\`, `\\`, "_", `\_`, "*", `\*`, "[", `\[`, "]", `\]`, "(", `\(`, ")", `\)`,
+	"~", `\~`, "`", "\\`", ">", `\>`, "#", `\#`, "+", `\+`, "-", `\-`, "=", `\=`,
+	"|", `\|`, "{", `\{`, "}", `\}`, ".", `\.`, "!", `\!`,
+)
+
+// markdownV2URLEscaper escapes the characters reserved inside the (...) part of a MarkdownV2 link.
+var markdownV2URLEscaper = strings.NewReplacer(`\`, `\\`, ")", `\)`)
+
+func (m ParseMode) valid() bool {
+	switch m {
+	case ParseModePlain, ParseModeMarkdownV2, ParseModeHTML:
+		return true
+	}
+	return false
+}
+
+// escape makes s safe to embed as literal text in a message of mode m.
+func (m ParseMode) escape(s string) string {
+	switch m {
+	case ParseModeMarkdownV2:
+		return markdownV2Escaper.Replace(s)
+	case ParseModeHTML:
+		return html.EscapeString(s)
+	}
+	return s
+}
+
+// bold escapes s and renders it in bold where the mode supports it.
+func (m ParseMode) bold(s string) string {
+	switch m {
+	case ParseModeMarkdownV2:
+		return "*" + m.escape(s) + "*"
+	case ParseModeHTML:
+		return "<b>" + m.escape(s) + "</b>"
+	}
+	return s
+}
+
+// link renders text as a link to href. Plain text has no links, so it returns "".
+func (m ParseMode) link(text, href string) string {
+	switch m {
+	case ParseModeMarkdownV2:
+		return "[" + m.escape(text) + "](" + markdownV2URLEscaper.Replace(href) + ")"
+	case ParseModeHTML:
+		return `<a href="` + html.EscapeString(href) + `">` + m.escape(text) + "</a>"
+	}
+	return ""
+}
+
+// currencyFlags maps currency codes to the flag of the country (or union) issuing them.
+// It covers the currencies rico.ge lists and a few common others.
+var currencyFlags = map[string]string{
+	"AED": "🇦🇪", "AMD": "🇦🇲", "AUD": "🇦🇺", "AZN": "🇦🇿", "BYN": "🇧🇾", "CAD": "🇨🇦",
+	"CHF": "🇨🇭", "CNY": "🇨🇳", "CZK": "🇨🇿", "DKK": "🇩🇰", "EUR": "🇪🇺", "GBP": "🇬🇧",
+	"GEL": "🇬🇪", "ILS": "🇮🇱", "JPY": "🇯🇵", "KZT": "🇰🇿", "NOK": "🇳🇴", "PLN": "🇵🇱",
+	"RUB": "🇷🇺", "SEK": "🇸🇪", "TRY": "🇹🇷", "UAH": "🇺🇦", "USD": "🇺🇸",
+}
+
+// flag returns the flag emoji of currency, or "" when there's none.
+func flag(currency string) string {
+	return currencyFlags[currency]
+}
+
+// MessageData is what message templates are executed with.
+type MessageData struct {
+	Currency   string
+	Flag       string // flag emoji of the currency, e.g. "🇺🇸", empty when unknown
+	Unit       string // what the values are quoted per, e.g. "USD", or "GEL → USD" when inverted
+	Inverted   bool   // values are currency per 1 GEL rather than GEL per 1 unit
+	Buy        float64
+	Sell       float64
+	Spread     float64 // |Sell - Buy|
+	BuyChange  string  // e.g. " (+0.4%)", empty on the first observation
+	SellChange string
+	BuyTrend   string // " ⬆️", " ⬇️" or " ➡️", empty on the first observation
+	SellTrend  string
+	Time       string // formatted in the checker's location
+	URL        string // page the rate was scraped from
+	Branch     string // rico.ge branch the rate is from, empty for the default page
+	Source     string // name of the source the rate came from, e.g. "rico.ge"
+	SourceLink bool   // the message should end with URL, see WithSourceLink
+
+	// transfer (non-cash) values, zero unless HasTransfer
+	HasTransfer  bool
+	TransferBuy  float64
+	TransferSell float64
+}
+
+// Default message templates. Templates can use the escape, bold and link functions,
+// which render according to the configured parse mode, and number, which formats a value
+// as set with WithNumberFormat.
+const (
+	defaultPlainTemplate = `{{.Time}} - {{with .Flag}}{{.}} {{end}}1 {{.Unit}}{{with .Branch}} ({{.}}){{end}} 
+	{{if .HasTransfer}}ნაღდი - {{end}}ყიდვა: {{number .Buy}}{{.BuyChange}}{{.BuyTrend}}, გაყიდვა: {{number .Sell}}{{.SellChange}}{{.SellTrend}}, სპრედი: {{number .Spread}}{{if .HasTransfer}}
+	უნაღდო - ყიდვა: {{number .TransferBuy}}, გაყიდვა: {{number .TransferSell}}{{end}}{{if .SourceLink}}
+{{.URL}}{{end}}`
+
+	defaultFormattedTemplate = `{{escape .Time}} {{escape "-"}} {{with .Flag}}{{.}} {{end}}{{bold (print "1 " .Unit)}}{{with .Branch}} {{escape (print "(" . ")")}}{{end}}
+{{if .HasTransfer}}{{bold "ნაღდი"}} {{escape "-"}} {{end}}{{bold "ყიდვა:"}} {{escape (print (number .Buy) .BuyChange .BuyTrend)}}, {{bold "გაყიდვა:"}} {{escape (print (number .Sell) .SellChange .SellTrend)}}, {{bold "სპრედი:"}} {{escape (number .Spread)}}
+{{- if .HasTransfer}}
+{{bold "უნაღდო"}} {{escape "-"}} {{bold "ყიდვა:"}} {{escape (number .TransferBuy)}}, {{bold "გაყიდვა:"}} {{escape (number .TransferSell)}}{{end}}
+{{link (or .Source "rico.ge") .URL}}`
+)
+
+// parseTemplate parses text as a message template for the given parse mode, formatting
+// values with number. An empty text selects the default template of the mode.
+func parseTemplate(text string, mode ParseMode, number func(float64) string) (*template.Template, error) {
+	if text == "" {
+		text = defaultFormattedTemplate
+		if mode == ParseModePlain {
+			text = defaultPlainTemplate
+		}
+	}
+	return template.New("message").Funcs(template.FuncMap{
+		"escape": mode.escape,
+		"bold":   mode.bold,
+		"link":   mode.link,
+		"number": number,
+	}).Parse(text)
+}
+
+// numberFormat is how values are written in messages, see WithNumberFormat.
+type numberFormat struct {
+	decimals  int
+	separator string
+}
+
+// numbers returns the configured number format.
+func (rc *RateChecker) numbers() numberFormat {
+	return numberFormat{decimals: rc.decimals, separator: rc.decimalSep}
+}
+
+// number formats v for display in messages, with the configured decimal places and
+// separator.
+func (rc *RateChecker) number(v float64) string {
+	return rc.numbers().number(v)
+}
+
+// number formats v with the format's decimal places and separator.
+func (f numberFormat) number(v float64) string {
+	return f.separate(strconv.FormatFloat(v, 'f', f.decimals, 64))
+}
+
+// percent formats a move of p percent with its sign and one decimal place, e.g. "+0.4%".
+func (f numberFormat) percent(p float64) string {
+	return f.separate(fmt.Sprintf("%+.1f%%", p))
+}
+
+// percentChange formats the relative move from old to new as " (+0.4%)".
+// It returns an empty string when old is zero.
+func (f numberFormat) percentChange(old, new float64) string {
+	if old == 0 {
+		return ""
+	}
+	return " (" + f.percent((new-old)/old*100) + ")"
+}
+
+// separate replaces the decimal point of a formatted number with the format's separator.
+func (f numberFormat) separate(s string) string {
+	if f.separator != "." && f.separator != "" {
+		s = strings.Replace(s, ".", f.separator, 1)
+	}
+	return s
+}
+
+// formatMessage builds the Telegram message text for rate observed at the given time.
+// prev is the previously seen rate, or nil on the first observation. With WithInvertedRates
+// both are shown per 1 GEL; the rates themselves are left as they are.
+func (rc *RateChecker) formatMessage(rate Rate, prev *Rate, at time.Time) (string, error) {
+	unit := rate.Currency
+	if rc.inverted {
+		unit = "GEL → " + rate.Currency
+		rate = rate.Invert()
+		if prev != nil {
+			inv := prev.Invert()
+			prev = &inv
+		}
+	}
+
+	data := MessageData{
+		Currency: rate.Currency,
+		Flag:     flag(rate.Currency),
+		Unit:     unit,
+		Inverted: rc.inverted,
+		Buy:      rate.Buy,
+		Sell:     rate.Sell,
+		Spread:   math.Abs(rate.Spread()), // inverting flips the sign
+		Time:     at.In(rc.location).Format(rc.timeFormat),
+		URL:      rc.url,
+		Branch:   rc.branch,
+
+		Source:     rate.Source,
+		SourceLink: rc.sourceLink,
+
+		HasTransfer:  rate.hasTransfer(),
+		TransferBuy:  rate.TransferBuy,
+		TransferSell: rate.TransferSell,
+	}
+	if prev != nil {
+		data.BuyChange = rc.numbers().percentChange(prev.Buy, rate.Buy)
+		data.SellChange = rc.numbers().percentChange(prev.Sell, rate.Sell)
+		data.BuyTrend = trend(prev.Buy, rate.Buy)
+		data.SellTrend = trend(prev.Sell, rate.Sell)
+	}
+
+	var b strings.Builder
+	if err := rc.template.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("executing message template: %w", err)
+	}
+	return b.String(), nil
+}
+
+// formatBatch renders rate changes batched into one message as plain text, the time
+// followed by one line per currency.
+func (rc *RateChecker) formatBatch(changes []Event, at time.Time) string {
+	var b strings.Builder
+	b.WriteString(at.In(rc.location).Format(rc.timeFormat))
+	if rc.branch != "" {
+		b.WriteString(" (" + rc.branch + ")")
+	}
+	for _, ev := range changes {
+		rate, prev, unit := ev.Rate, ev.Previous, ev.Rate.Currency
+		if rc.inverted {
+			unit = "GEL → " + rate.Currency
+			rate = rate.Invert()
+			if prev != nil {
+				inv := prev.Invert()
+				prev = &inv
+			}
+		}
+
+		var buyChange, sellChange string
+		if prev != nil {
+			buyChange = rc.numbers().percentChange(prev.Buy, rate.Buy) + trend(prev.Buy, rate.Buy)
+			sellChange = rc.numbers().percentChange(prev.Sell, rate.Sell) + trend(prev.Sell, rate.Sell)
+		}
+		b.WriteString("\n")
+		if f := flag(rate.Currency); f != "" {
+			b.WriteString(f + " ")
+		}
+		fmt.Fprintf(&b, "1 %s: ყიდვა %s%s, გაყიდვა %s%s", unit, rc.number(rate.Buy), buyChange, rc.number(rate.Sell), sellChange)
+		if rate.hasTransfer() {
+			fmt.Fprintf(&b, " (უნაღდო: ყიდვა %s, გაყიდვა %s)", rc.number(rate.TransferBuy), rc.number(rate.TransferSell))
+		}
+	}
+	if rc.sourceLink {
+		b.WriteString("\n" + rc.url)
+	}
+	return b.String()
+}
+
+// formatStartup renders the message announcing that the checker started, with the
+// current rates of the watched currencies.
+func (rc *RateChecker) formatStartup(rates map[string]Rate) string {
+	return rc.formatRates("🔄 rate checker started, current rates:", rates)
+}
+
+// formatHeartbeat renders the periodic message confirming the checker is alive, with the
+// current rates of the watched currencies.
+func (rc *RateChecker) formatHeartbeat(rates map[string]Rate) string {
+	return rc.formatRates("💓 heartbeat, current rates:", rates)
+}
+
+// formatRates renders heading followed by one line per watched currency.
+func (rc *RateChecker) formatRates(heading string, rates map[string]Rate) string {
+	var b strings.Builder
+	b.WriteString(heading)
+	for _, c := range rc.currencies {
+		r := rates[c]
+		fmt.Fprintf(&b, "\n%s: ყიდვა %s, გაყიდვა %s", c, rc.number(r.Buy), rc.number(r.Sell))
+	}
+	return b.String()
+}
+
+// validateTimeFormat checks that layout is a time layout built from Go's reference time
+// (Mon Jan 2 15:04:05 MST 2006), so timestamps don't come out as the literal text.
+func validateTimeFormat(layout string) error {
+	// not the reference time itself, which a layout formats back into the layout
+	sample := time.Date(2001, time.November, 23, 9, 30, 45, 0, time.UTC)
+	formatted := sample.Format(layout)
+	if layout == "" || formatted == layout {
+		return fmt.Errorf("time format %q has no date or time elements", layout)
+	}
+	if _, err := time.Parse(layout, formatted); err != nil {
+		return fmt.Errorf("time format %q: %w", layout, err)
+	}
+	return nil
+}
+
+// trend renders the direction of the move from old to new as an arrow.
+func trend(old, new float64) string {
+	switch {
+	case new > old:
+		return " ⬆️"
+	case new < old:
+		return " ⬇️"
+	}
+	return " ➡️"
+}
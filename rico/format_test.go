@@ -0,0 +1,104 @@
+package rico
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseModeEscape(t *testing.T) {
+	tests := []struct {
+		mode ParseMode
+		in   string
+		want string
+	}{
+		{ParseModeMarkdownV2, "2.7000", `2\.7000`},
+		{ParseModeMarkdownV2, "$5 - (USD)", `$5 \- \(USD\)`},
+		{ParseModeMarkdownV2, `a_b*c[d]~e` + "`" + `>#+=|{}!\`, `a\_b\*c\[d\]\~e` + "\\`" + `\>\#\+\=\|\{\}\!\\`},
+		{ParseModeHTML, "2.70 - (1 < 2) & $", "2.70 - (1 &lt; 2) &amp; $"},
+		{ParseModeHTML, `"quoted"`, "&#34;quoted&#34;"},
+		{ParseModePlain, "2.70 - (USD)", "2.70 - (USD)"},
+	}
+	for _, tt := range tests {
+		if got := tt.mode.escape(tt.in); got != tt.want {
+			t.Errorf("%q.escape(%q) = %q, want %q", tt.mode, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseModeBold(t *testing.T) {
+	tests := []struct {
+		mode ParseMode
+		in   string
+		want string
+	}{
+		{ParseModeMarkdownV2, "1 USD (cash).", `*1 USD \(cash\)\.*`},
+		{ParseModeMarkdownV2, "a*b", `*a\*b*`},
+		{ParseModeHTML, "1 USD <cash>", "<b>1 USD &lt;cash&gt;</b>"},
+		{ParseModePlain, "1 USD", "1 USD"},
+	}
+	for _, tt := range tests {
+		if got := tt.mode.bold(tt.in); got != tt.want {
+			t.Errorf("%q.bold(%q) = %q, want %q", tt.mode, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseModeLink(t *testing.T) {
+	tests := []struct {
+		mode       ParseMode
+		text, href string
+		want       string
+	}{
+		{ParseModeMarkdownV2, "rico.ge", "https://www.rico.ge/ka", `[rico\.ge](https://www.rico.ge/ka)`},
+		{ParseModeMarkdownV2, "rates (ka)", "https://example.com/a_(b)?x=1-2", `[rates \(ka\)](https://example.com/a_(b\)?x=1-2)`},
+		{ParseModeMarkdownV2, "back", `https://example.com/a\b`, `[back](https://example.com/a\\b)`},
+		{ParseModeHTML, "rico.ge", "https://example.com/?a=1&b=(2)", `<a href="https://example.com/?a=1&amp;b=(2)">rico.ge</a>`},
+		{ParseModeHTML, "<b>", `https://example.com/"x"`, `<a href="https://example.com/&#34;x&#34;">&lt;b&gt;</a>`},
+		{ParseModePlain, "rico.ge", "https://www.rico.ge/ka", ""},
+	}
+	for _, tt := range tests {
+		if got := tt.mode.link(tt.text, tt.href); got != tt.want {
+			t.Errorf("%q.link(%q, %q) = %q, want %q", tt.mode, tt.text, tt.href, got, tt.want)
+		}
+	}
+}
+
+func TestFormatMessageNumberFormat(t *testing.T) {
+	tests := []struct {
+		decimals  int
+		separator string
+		want      string
+	}{
+		{4, ".", "2.7108 (+0.4%)"},
+		{2, ",", "2,71 (+0,4%)"},
+	}
+	for _, tt := range tests {
+		rc, err := newRateChecker(nil, WithNumberFormat(tt.decimals, tt.separator), WithMessageTemplate("{{number .Buy}}{{.BuyChange}}"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		prev := Rate{Currency: "USD", Buy: 2.7, Sell: 2.72}
+		got, err := rc.formatMessage(Rate{Currency: "USD", Buy: 2.7108, Sell: 2.72}, &prev, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("WithNumberFormat(%d, %q): message = %q, want %q", tt.decimals, tt.separator, got, tt.want)
+		}
+	}
+}
+
+func TestBigMoveNumberFormat(t *testing.T) {
+	rec := &recorder{}
+	rc := newTestChecker(t, rec, WithNumberFormat(2, ","), WithBigMove(BigMove{Percent: 1}))
+	prev := Rate{Currency: "USD", Buy: 2.7, Sell: 2.72}
+	if err := rc.checkBigMove(context.Background(), &prev, Rate{Currency: "USD", Buy: 2.754, Sell: 2.72}); err != nil {
+		t.Fatal(err)
+	}
+	events := rec.take()
+	if want := "buy 2,70 → 2,75 (+2,0%), sell 2,72 → 2,72 (+0,0%)"; len(events) != 1 || !strings.Contains(events[0].Text, want) {
+		t.Errorf("got %+v, want one alert containing %q", events, want)
+	}
+}
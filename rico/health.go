@@ -0,0 +1,147 @@
+package rico
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+	"net/http"
+	"time"
+)
+
+const defaultHealthStaleIntervals = 3
+
+// healthResponse is the JSON body served by the health endpoint.
+type healthResponse struct {
+	Status      string          `json:"status"`
+	LastSuccess time.Time       `json:"last_success"`
+	Rates       map[string]Rate `json:"rates"`
+}
+
+// Status is a snapshot of how the checker is doing, see RateChecker.Status.
+type Status struct {
+	LastFetch  time.Time       // time of the last successful fetch, zero before the first
+	LastChange time.Time       // time a stored rate last changed, zero before the first
+	Failures   int             // fetches failed in a row since the last successful one
+	Rates      map[string]Rate // last stored rate per currency code
+}
+
+// Status returns a snapshot of the checker's health without needing metrics. It's safe to
+// call while Run is active.
+func (rc *RateChecker) Status() Status {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+	return Status{
+		LastFetch:  rc.lastSuccess,
+		LastChange: rc.lastChange,
+		Failures:   rc.failures,
+		Rates:      maps.Clone(rc.rates),
+	}
+}
+
+// HealthHandler returns a handler reporting the time of the last successful fetch and
+// the last stored rates. It responds 503 when no fetch succeeded within the configured
+// number of intervals, except on days Run skips.
+func (rc *RateChecker) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rc.mu.RLock()
+		resp := healthResponse{
+			Status:      "ok",
+			LastSuccess: rc.lastSuccess,
+			Rates:       maps.Clone(rc.rates),
+		}
+		rc.mu.RUnlock()
+
+		code := http.StatusOK
+		now := rc.clock.Now()
+		maxAge := time.Duration(rc.healthStaleIntervals) * rc.interval
+		if _, skip := rc.skipDay(now); skip {
+			// nothing is fetched on skipped days, so an old fetch is expected
+			resp.Status = "skipped_day"
+		} else if resp.LastSuccess.IsZero() || now.Sub(resp.LastSuccess) > maxAge {
+			resp.Status = "stale"
+			code = http.StatusServiceUnavailable
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			rc.logger.Error("writing health response", "error", err)
+		}
+	})
+}
+
+// RateHandler returns a handler serving the last stored rate as JSON. The currency query
+// parameter selects the currency, defaulting to the first watched one. It responds 503
+// until a fetch has succeeded and 404 for a currency that isn't watched.
+func (rc *RateChecker) RateHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		currency := rc.currencies[0]
+		if c := r.URL.Query().Get("currency"); c != "" {
+			currency = normalizeCurrency(c)
+		}
+		if !rc.watches(currency) {
+			http.Error(w, "currency not watched", http.StatusNotFound)
+			return
+		}
+
+		rate, ok := rc.Rate(currency)
+		if !ok {
+			http.Error(w, "no rate fetched yet", http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(rate); err != nil {
+			rc.logger.Error("writing rate response", "error", err)
+		}
+	})
+}
+
+// startServer starts the embedded HTTP server when an address is configured.
+// stopServer shuts it down.
+func (rc *RateChecker) startServer() {
+	if rc.healthAddr == "" {
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/healthz", rc.HealthHandler())
+	mux.Handle("/rate", rc.RateHandler())
+	if rc.metrics != nil {
+		mux.Handle("/metrics", rc.MetricsHandler())
+	}
+	srv := &http.Server{
+		Addr:              rc.healthAddr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		rc.logger.Info("serving HTTP endpoints", "addr", rc.healthAddr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			rc.logger.Error("HTTP server stopped", "error", err)
+		}
+	}()
+
+	rc.serverMu.Lock()
+	rc.server = srv
+	rc.serverMu.Unlock()
+}
+
+// stopServer shuts down the embedded HTTP server, if it's running.
+func (rc *RateChecker) stopServer() {
+	rc.serverMu.Lock()
+	srv := rc.server
+	rc.server = nil
+	rc.serverMu.Unlock()
+	if srv == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		rc.logger.Error("shutting down HTTP server", "error", err)
+	}
+}
@@ -0,0 +1,56 @@
+package rico
+
+import "time"
+
+const defaultHistorySize = 60
+
+// ring is a fixed-size buffer that keeps the most recent records, overwriting the oldest.
+type ring struct {
+	buf  []RateRecord
+	next int // index the next record is written to
+	full bool
+}
+
+func newRing(size int) *ring {
+	return &ring{buf: make([]RateRecord, size)}
+}
+
+func (r *ring) add(rec RateRecord) {
+	if len(r.buf) == 0 {
+		return
+	}
+	r.buf[r.next] = rec
+	r.next = (r.next + 1) % len(r.buf)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// newestFirst returns a copy of the buffered records, most recent first.
+func (r *ring) newestFirst() []RateRecord {
+	n := r.next
+	if r.full {
+		n = len(r.buf)
+	}
+	ret := make([]RateRecord, 0, n)
+	for i := 1; i <= n; i++ {
+		ret = append(ret, r.buf[(r.next-i+len(r.buf))%len(r.buf)])
+	}
+	return ret
+}
+
+// recordRecent adds a rate observed at the given time to the in-memory history.
+func (rc *RateChecker) recordRecent(rate Rate, at time.Time) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.recent.add(RateRecord{Rate: rate, Time: at})
+}
+
+// RecentRates returns the rates observed by the most recent checks, newest first.
+// Every fetched rate is kept, changed or not, up to the size set with WithHistorySize.
+// Unlike LastRates it doesn't need a Store; the history is lost on restart.
+func (rc *RateChecker) RecentRates() []RateRecord {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+	return rc.recent.newestFirst()
+}
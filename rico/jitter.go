@@ -0,0 +1,35 @@
+package rico
+
+import (
+	"math/rand"
+	"time"
+)
+
+// jittered scales d by a random factor within the jitter fraction, e.g. to between 0.8d and
+// 1.2d for 0.2. Without jitter it returns d.
+func (rc *RateChecker) jittered(d time.Duration) time.Duration {
+	if rc.jitter == 0 || d <= 0 {
+		return d
+	}
+	return time.Duration(float64(d) * (1 + rc.jitter*(2*rc.random()-1)))
+}
+
+// startDelay returns how long Run waits before its first check, a random part of the
+// jitter fraction of the interval, so checkers started together don't stay in lockstep.
+func (rc *RateChecker) startDelay() time.Duration {
+	if rc.jitter == 0 {
+		return 0
+	}
+	return time.Duration(float64(rc.interval) * rc.jitter * rc.random())
+}
+
+// random returns a number in [0, 1) from the checker's random source.
+func (rc *RateChecker) random() float64 {
+	// sources fetch concurrently, and a rand.Rand isn't safe for concurrent use
+	rc.randMu.Lock()
+	defer rc.randMu.Unlock()
+	if rc.rand == nil {
+		rc.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return rc.rand.Float64()
+}
@@ -0,0 +1,79 @@
+package rico
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// metrics holds the Prometheus collectors of a RateChecker. A nil *metrics is valid
+// and records nothing, so call sites don't need to check whether metrics are enabled.
+type metrics struct {
+	registry     *prometheus.Registry
+	fetches      *prometheus.CounterVec
+	fetchLatency prometheus.Histogram
+	buy          *prometheus.GaugeVec
+	sell         *prometheus.GaugeVec
+}
+
+func newMetrics() *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		fetches: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "rico_fetches_total",
+			Help: "Number of rate page fetches by result.",
+		}, []string{"result"}),
+		fetchLatency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "rico_fetch_duration_seconds",
+			Help:    "Latency of rate page requests.",
+			Buckets: prometheus.DefBuckets,
+		}),
+		buy: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "rico_rate_buy",
+			Help: "Current buy rate in GEL.",
+		}, []string{"currency"}),
+		sell: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "rico_rate_sell",
+			Help: "Current sell rate in GEL.",
+		}, []string{"currency"}),
+	}
+	m.registry.MustRegister(m.fetches, m.fetchLatency, m.buy, m.sell)
+	return m
+}
+
+func (m *metrics) observeFetch(err error) {
+	if m == nil {
+		return
+	}
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	m.fetches.WithLabelValues(result).Inc()
+}
+
+func (m *metrics) observeLatency(d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.fetchLatency.Observe(d.Seconds())
+}
+
+func (m *metrics) observeRate(rate Rate) {
+	if m == nil {
+		return
+	}
+	m.buy.WithLabelValues(rate.Currency).Set(rate.Buy)
+	m.sell.WithLabelValues(rate.Currency).Set(rate.Sell)
+}
+
+// MetricsHandler returns the Prometheus handler exposing the checker's metrics.
+// It responds 404 when metrics aren't enabled with WithMetrics.
+func (rc *RateChecker) MetricsHandler() http.Handler {
+	if rc.metrics == nil {
+		return http.NotFoundHandler()
+	}
+	return promhttp.HandlerFor(rc.metrics.registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,190 @@
+package rico
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// EventKind tells what an Event reports.
+type EventKind int
+
+const (
+	// EventRateChange reports that the rate of a currency changed.
+	EventRateChange EventKind = iota
+	// EventAlert carries a free-form message, such as a daily summary or a scraper alert.
+	EventAlert
+	// EventRateChanges reports the rate changes of several currencies at once, see
+	// WithBatchedMessages. Text holds them rendered one line per currency.
+	EventRateChanges
+	// EventHeartbeat carries the current rates sent periodically whether or not they
+	// changed, see WithHeartbeat. Text holds them rendered.
+	EventHeartbeat
+	// EventBigMove reports that a rate moved by at least the big move threshold between
+	// two checks, see WithBigMove. Text holds the alert rendered.
+	EventBigMove
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case EventRateChange:
+		return "rate_change"
+	case EventAlert:
+		return "alert"
+	case EventRateChanges:
+		return "rate_changes"
+	case EventHeartbeat:
+		return "heartbeat"
+	case EventBigMove:
+		return "big_move"
+	}
+	return fmt.Sprintf("EventKind(%d)", int(k))
+}
+
+// Event is what notifiers are told about.
+type Event struct {
+	Kind     EventKind
+	Rate     Rate   // new rate of a rate change or big move
+	Previous *Rate  // rate before the change, nil on the first observation
+	Currency string // currency an alert is about, empty for alerts about none in particular
+	Text     string
+	Time     time.Time
+	Changes  []Event // the rate changes of an EventRateChanges
+}
+
+// currencies returns the currencies whose rate changes ev reports, or the one an alert
+// is about.
+func (ev Event) currencies() []string {
+	switch ev.Kind {
+	case EventRateChange, EventBigMove:
+		return []string{ev.Rate.Currency}
+	case EventAlert:
+		if ev.Currency != "" {
+			return []string{ev.Currency}
+		}
+	case EventRateChanges:
+		var ret []string
+		for _, c := range ev.Changes {
+			ret = append(ret, c.Rate.Currency)
+		}
+		return ret
+	}
+	return nil
+}
+
+// changes formats how buy and sell moved compared to the previous rate in format f, e.g.
+// " (+0.4%)". Both are empty on the first observation.
+func (ev Event) changes(f numberFormat) (buy, sell string) {
+	if ev.Previous == nil {
+		return "", ""
+	}
+	return f.percentChange(ev.Previous.Buy, ev.Rate.Buy), f.percentChange(ev.Previous.Sell, ev.Rate.Sell)
+}
+
+// Notifier delivers events to some destination, e.g. a Telegram channel or a webhook.
+type Notifier interface {
+	Notify(ctx context.Context, ev Event) error
+}
+
+// notify passes ev to every notifier. A failing notifier doesn't keep the others from
+// being notified; their errors are joined.
+func (rc *RateChecker) notify(ctx context.Context, ev Event) error {
+	if rc.dryRun {
+		text, err := rc.eventText(ev)
+		if err != nil {
+			return err
+		}
+		rc.logger.Info("dry run, not sending message", "kind", ev.Kind, "text", text)
+		return nil
+	}
+
+	var errs []error
+	for _, n := range rc.notifiers {
+		if err := n.Notify(ctx, ev); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// alert notifies about a free-form plain text message concerning currency, empty for one
+// about no currency in particular, or holds it back during the quiet hours.
+func (rc *RateChecker) alert(ctx context.Context, currency, text string) error {
+	return rc.notifyUnlessQuiet(ctx, Event{Kind: EventAlert, Currency: currency, Text: text, Time: rc.clock.Now()})
+}
+
+// eventText renders ev as message text: the message template for rate changes, the
+// escaped text for alerts and batched changes.
+func (rc *RateChecker) eventText(ev Event) (string, error) {
+	if ev.Kind == EventRateChange {
+		return rc.formatMessage(ev.Rate, ev.Previous, ev.Time)
+	}
+	return rc.parseMode.escape(ev.Text), nil
+}
+
+// webhookNotifier POSTs events as JSON to a URL.
+type webhookNotifier struct {
+	client  *http.Client
+	url     string
+	timeout time.Duration
+}
+
+// webhookPayload is the JSON body POSTed by webhookNotifier.
+type webhookPayload struct {
+	Type     string    `json:"type"`
+	Rate     *Rate     `json:"rate,omitempty"`
+	Previous *Rate     `json:"previous,omitempty"`
+	Text     string    `json:"text,omitempty"`
+	Time     time.Time `json:"time"`
+
+	Changes []webhookPayload `json:"changes,omitempty"`
+}
+
+// newWebhookPayload converts ev, and the changes it batches, into its JSON form.
+func newWebhookPayload(ev Event) webhookPayload {
+	payload := webhookPayload{
+		Type:     ev.Kind.String(),
+		Previous: ev.Previous,
+		Text:     ev.Text,
+		Time:     ev.Time,
+	}
+	if ev.Kind == EventRateChange || ev.Kind == EventBigMove {
+		payload.Rate = &ev.Rate
+	}
+	for _, c := range ev.Changes {
+		payload.Changes = append(payload.Changes, newWebhookPayload(c))
+	}
+	return payload
+}
+
+// Notify implements Notifier.
+func (w *webhookNotifier) Notify(ctx context.Context, ev Event) error {
+	body, err := json.Marshal(newWebhookPayload(ev))
+	if err != nil {
+		return fmt.Errorf("webhook: encoding payload: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, w.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("webhook: creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook: sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook: received non-2xx status: %d", resp.StatusCode)
+	}
+	return nil
+}
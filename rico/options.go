@@ -0,0 +1,581 @@
+package rico
+
+import (
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Option configures optional RateChecker settings.
+type Option func(*RateChecker)
+
+// WithInterval sets how often Run checks for rate changes.
+func WithInterval(d time.Duration) Option {
+	return func(rc *RateChecker) {
+		rc.interval = d
+	}
+}
+
+// WithTimezone sets the IANA time zone (e.g. "Europe/Berlin") that message timestamps
+// and daily summary boundaries use. Empty keeps the default, Asia/Tbilisi. The times
+// shown on rico.ge are always read as Tbilisi time.
+func WithTimezone(name string) Option {
+	return func(rc *RateChecker) {
+		rc.timezone = name
+	}
+}
+
+// WithTimeFormat sets the layout of message timestamps, written with Go's reference time,
+// e.g. "02.01.2006 15:04". Defaults to "Jan 2 15:04:05".
+func WithTimeFormat(layout string) Option {
+	return func(rc *RateChecker) {
+		rc.timeFormat = layout
+	}
+}
+
+// WithURL overrides the page rates are scraped from, e.g. "https://www.rico.ge/en".
+// An empty url keeps the default.
+func WithURL(url string) Option {
+	return func(rc *RateChecker) {
+		rc.url = url
+	}
+}
+
+// WithBranch scrapes the rates of a single rico.ge branch, e.g. "batumi", by adding it to
+// the page URL as the branch query parameter. Messages name the branch. Empty, the
+// default, scrapes whatever the page shows without one.
+func WithBranch(branch string) Option {
+	return func(rc *RateChecker) {
+		rc.branch = strings.TrimSpace(branch)
+	}
+}
+
+// WithRetry sets how many times a failed scrape request is attempted in total and
+// the delay before the first retry. The delay doubles after every further attempt.
+func WithRetry(attempts int, baseDelay time.Duration) Option {
+	return func(rc *RateChecker) {
+		rc.retryAttempts = attempts
+		rc.retryDelay = baseDelay
+	}
+}
+
+// WithRetryBudget caps how long a check may keep retrying failed requests: a retry whose
+// backoff delay would end more than d after the check started isn't made, and the last
+// error is returned instead, so slow failures don't run into the next interval. It
+// applies on top of the attempts set with WithRetry. Zero, the default, is unlimited.
+func WithRetryBudget(d time.Duration) Option {
+	return func(rc *RateChecker) {
+		rc.retryBudget = d
+	}
+}
+
+// WithZeroRetries fetches the page again up to n times right away when a rate on it
+// parses as zero, before failing the check with ErrZeroRate. Zero, the default, turns
+// the retries off.
+func WithZeroRetries(n int) Option {
+	return func(rc *RateChecker) {
+		rc.zeroRetries = n
+	}
+}
+
+// WithCacheTTL sets how long FetchCurrentRate returns the rates of the last fetch instead
+// of scraping the page again. Checks always scrape and refresh the cached rates. Defaults
+// to the polling interval; a negative ttl turns the cache off.
+func WithCacheTTL(ttl time.Duration) Option {
+	return func(rc *RateChecker) {
+		rc.cacheTTL = ttl
+	}
+}
+
+// WithStore persists every detected rate change to s.
+func WithStore(s Store) Option {
+	return func(rc *RateChecker) {
+		rc.store = s
+	}
+}
+
+// WithJitter randomizes the retry backoff delays by up to fraction of each delay either way,
+// and delays the first check of Run by up to fraction of the interval, so checkers started
+// at the same time don't all hit rico.ge in the same second. fraction must be between 0
+// and 1; zero, the default, disables jitter.
+func WithJitter(fraction float64) Option {
+	return func(rc *RateChecker) {
+		rc.jitter = fraction
+	}
+}
+
+// WithRandSource makes the jitter draw from src, e.g. rand.NewSource(1) for a repeatable
+// sequence in tests. nil keeps a source seeded from the current time.
+func WithRandSource(src rand.Source) Option {
+	return func(rc *RateChecker) {
+		if src != nil {
+			rc.rand = rand.New(src)
+		}
+	}
+}
+
+// WithStateFile keeps the last rates in the JSON file at path, rewritten whenever one changes,
+// and restores them on startup, so a restart doesn't send every rate again as a change. It's
+// a lighter alternative to WithStore. A missing or corrupt file starts fresh.
+func WithStateFile(path string) Option {
+	return func(rc *RateChecker) {
+		rc.statePath = path
+	}
+}
+
+// WithHistorySize sets how many observed rates RecentRates keeps in memory.
+// Defaults to 60; zero disables the history.
+func WithHistorySize(n int) Option {
+	return func(rc *RateChecker) {
+		rc.historySize = n
+	}
+}
+
+// WithMinChange only sends a message when buy or sell moved by at least delta GEL
+// compared to the last sent rate.
+func WithMinChange(delta float64) Option {
+	return func(rc *RateChecker) {
+		rc.minChange = delta
+	}
+}
+
+// WithMinPercentChange only sends a message when buy or sell moved by at least pct
+// percent compared to the last sent rate.
+func WithMinPercentChange(pct float64) Option {
+	return func(rc *RateChecker) {
+		rc.minPercentChange = pct
+	}
+}
+
+// WithBigMove sends an extra alert when a rate moves by at least b.Percent between two
+// checks, optionally to other Telegram chats or with a mention. The normal change
+// message is still sent as well. See BigMove.
+func WithBigMove(b BigMove) Option {
+	return func(rc *RateChecker) {
+		rc.bigMove = &b
+	}
+}
+
+// WithParseMode sends messages formatted for the given Telegram parse mode,
+// with bold labels and a link to the source page. Plain text is the default.
+func WithParseMode(mode ParseMode) Option {
+	return func(rc *RateChecker) {
+		rc.parseMode = mode
+	}
+}
+
+// WithSourceLink ends plain text rate messages with the URL of the page the rates came
+// from, so readers know where they're from. Messages with a parse mode always end with a
+// link to it, named after the source.
+func WithSourceLink(enabled bool) Option {
+	return func(rc *RateChecker) {
+		rc.sourceLink = enabled
+	}
+}
+
+// WithInvertedRates shows rates in messages as currency per 1 GEL (1/buy and 1/sell)
+// instead of GEL per 1 unit of the currency. Stored and compared rates are unaffected.
+func WithInvertedRates(inverted bool) Option {
+	return func(rc *RateChecker) {
+		rc.inverted = inverted
+	}
+}
+
+// WithNumberFormat sets how buy, sell and other values are written in messages: with the
+// given number of decimal places and separator, e.g. 2 and "," for "2,70". It only changes
+// the text; stored and compared values keep the precision set with WithPrecision. Defaults
+// to 4 places and ".". An empty separator keeps ".".
+func WithNumberFormat(decimals int, separator string) Option {
+	return func(rc *RateChecker) {
+		rc.decimals = decimals
+		rc.decimalSep = separator
+	}
+}
+
+// WithMessageTemplate replaces the default message with a text/template executed
+// with MessageData, e.g. `{{.Currency}}: {{printf "%.2f" .Buy}} / {{printf "%.2f" .Sell}}`.
+// With a parse mode set, dynamic values should go through the escape function.
+func WithMessageTemplate(text string) Option {
+	return func(rc *RateChecker) {
+		rc.templateText = text
+	}
+}
+
+// WithSpreadAlert sends an extra alert when the spread (sell - buy) of a watched
+// currency widens beyond threshold, which often signals market stress.
+func WithSpreadAlert(threshold float64) Option {
+	return func(rc *RateChecker) {
+		rc.spreadAlert = threshold
+	}
+}
+
+// WithEMACross keeps a short and a long exponential moving average of each currency's
+// sell price, over the given numbers of checks, and alerts when the short one crosses the
+// long one. It smooths out single-tick noise that plain change messages react to.
+func WithEMACross(shortPeriod, longPeriod int) Option {
+	return func(rc *RateChecker) {
+		rc.emaShort, rc.emaLong = shortPeriod, longPeriod
+	}
+}
+
+// WithFrozenAlert sends an alert when a watched rate stays exactly the same for longer
+// than window while fetches keep succeeding, which can mean the page stopped updating.
+func WithFrozenAlert(window time.Duration) Option {
+	return func(rc *RateChecker) {
+		rc.frozenAfter = window
+	}
+}
+
+// WithHealthServer serves /healthz and /rate on addr (e.g. ":8080") while Run is active.
+// The check reports 503 once no fetch succeeded for staleIntervals polling intervals;
+// zero selects the default of 3.
+func WithHealthServer(addr string, staleIntervals int) Option {
+	return func(rc *RateChecker) {
+		rc.healthAddr = addr
+		rc.healthStaleIntervals = staleIntervals
+	}
+}
+
+// WithMetrics enables Prometheus metrics, served by MetricsHandler and on /metrics
+// of the embedded server.
+func WithMetrics() Option {
+	return func(rc *RateChecker) {
+		rc.metrics = newMetrics()
+	}
+}
+
+// WithLogger sets the structured logger used by the checker. A nil logger keeps the
+// default text handler writing to stderr.
+func WithLogger(l *slog.Logger) Option {
+	return func(rc *RateChecker) {
+		if l != nil {
+			rc.logger = l
+		}
+	}
+}
+
+// WithDryRun logs every rendered message instead of notifying anyone.
+// Change detection and storage work as usual.
+func WithDryRun(dryRun bool) Option {
+	return func(rc *RateChecker) {
+		rc.dryRun = dryRun
+	}
+}
+
+// WithHTTPClient replaces the default HTTP client, e.g. to talk to an httptest server.
+// Unlike the default client, which honors the HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+// environment variables, c only uses a proxy when its transport is configured to.
+// nil keeps the default.
+func WithHTTPClient(c *http.Client) Option {
+	return func(rc *RateChecker) {
+		if c != nil {
+			rc.client = c
+		}
+	}
+}
+
+// WithProxyURL sends every request, scraping and notifying, through the proxy at url,
+// e.g. "http://proxy.internal:3128", instead of the one set in the environment. It also
+// applies to a client given with WithHTTPClient, as long as its transport is an
+// *http.Transport; the client itself is left unchanged.
+func WithProxyURL(url string) Option {
+	return func(rc *RateChecker) {
+		rc.proxyURL = url
+	}
+}
+
+// WithInsecureTLS turns off TLS certificate verification for every request, so tests can
+// talk to an httptest.NewTLSServer standing in for rico.ge or Telegram. It's meant for
+// tests only: with it, anyone in the network path can read and change the traffic,
+// including the bot token. Off by default. The same transport restriction as for
+// WithProxyURL applies.
+func WithInsecureTLS(insecure bool) Option {
+	return func(rc *RateChecker) {
+		rc.insecureTLS = insecure
+	}
+}
+
+// WithTimeouts sets the deadline of a single scrape request and of a single Telegram
+// request. Both default to 10 seconds.
+func WithTimeouts(fetch, send time.Duration) Option {
+	return func(rc *RateChecker) {
+		rc.fetchTimeout = fetch
+		rc.sendTimeout = send
+	}
+}
+
+// WithBackupBotTokens adds bot tokens to send with when Telegram rejects or rate limits the
+// current one, tried in order after the token passed to NewRateChecker. The token that last
+// worked stays in use. Each of them must belong to a bot that can post to the channels.
+func WithBackupBotTokens(tokens ...string) Option {
+	return func(rc *RateChecker) {
+		rc.backupTokens = append(rc.backupTokens, tokens...)
+	}
+}
+
+// WithAuthFailureLimit stops sending to Telegram once it rejected the bot token n times
+// in a row, e.g. after the token was revoked, instead of failing every check from then on.
+// TokenRevoked tells when that happened. Defaults to 3; zero never stops.
+func WithAuthFailureLimit(n int) Option {
+	return func(rc *RateChecker) {
+		rc.authLimit = n
+	}
+}
+
+// WithMaxBodySize sets the largest page, in bytes, that is read before giving up with
+// ErrBodyTooLarge. Defaults to 5 MiB.
+func WithMaxBodySize(n int64) Option {
+	return func(rc *RateChecker) {
+		rc.maxBodySize = n
+	}
+}
+
+// WithParseFailureDump keeps the first maxBytes of a page whose rate table fails to parse,
+// to see what the site actually served after a layout change. They're written to a new
+// file in dir, or logged when dir is empty. Off by default.
+func WithParseFailureDump(maxBytes int, dir string) Option {
+	return func(rc *RateChecker) {
+		rc.dumpSize = maxBytes
+		rc.dumpDir = dir
+	}
+}
+
+// WithClock makes the checker read the current time from c instead of the wall clock,
+// e.g. to test day boundaries or staleness without waiting. The polling interval still
+// runs on real time. nil keeps the wall clock.
+func WithClock(c Clock) Option {
+	return func(rc *RateChecker) {
+		if c != nil {
+			rc.clock = c
+		}
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent when scraping the rates page.
+func WithUserAgent(ua string) Option {
+	return func(rc *RateChecker) {
+		rc.userAgent = ua
+	}
+}
+
+// WithScraperAlert sends a one-time alert when the rate table
+// can't be found on the page, instead of only failing the check.
+func WithScraperAlert(enabled bool) Option {
+	return func(rc *RateChecker) {
+		rc.alertOnMissingTable = enabled
+	}
+}
+
+// WithDailySummary sends an open/high/low/close summary of every watched currency
+// once a day, on the first check after midnight in the checker's timezone.
+func WithDailySummary(enabled bool) Option {
+	return func(rc *RateChecker) {
+		rc.dailySummary = enabled
+	}
+}
+
+// WithTelegramCommands makes Run answer commands sent to the Telegram bot, turning it from
+// push-only into interactive. "/rate" replies with the last stored rates, "/rate EUR" with
+// those of the given currencies. "/subscribe EUR" makes a chat get the rate changes of EUR
+// (plain "/subscribe" of every currency) and "/unsubscribe" undoes it; configured channels
+// get every currency until they subscribe. With a SubscriptionStore the subscriptions
+// survive restarts. Updates are read by long-polling getUpdates, so the bot must not have
+// a webhook set.
+func WithTelegramCommands(enabled bool) Option {
+	return func(rc *RateChecker) {
+		rc.commands = enabled
+	}
+}
+
+// WithWebhook POSTs every event as JSON to url, in addition to or instead of Telegram.
+func WithWebhook(url string) Option {
+	return func(rc *RateChecker) {
+		rc.webhookURLs = append(rc.webhookURLs, url)
+	}
+}
+
+// WithNotifier adds a custom notifier that is told about every event.
+func WithNotifier(n Notifier) Option {
+	return func(rc *RateChecker) {
+		rc.notifiers = append(rc.notifiers, n)
+	}
+}
+
+// WithOnRateChange calls fn whenever CheckForRateChange detects a rate change, with the
+// rate compared against (zero on the first check) and the new one, e.g. to feed a cache.
+// It runs synchronously after the new rate is stored and before any notification is sent,
+// for every change, including those not sent because of quiet hours, the dedup window or
+// disabled change notifications. Several callbacks run in the order they were added.
+func WithOnRateChange(fn func(old, new Rate)) Option {
+	return func(rc *RateChecker) {
+		if fn != nil {
+			rc.onChange = append(rc.onChange, fn)
+		}
+	}
+}
+
+// WithUpdates delivers every detected rate change on the channel returned by Updates,
+// buffering up to buffer changes, which must be at least 1. Like WithOnRateChange it
+// covers changes that aren't sent. A full buffer drops its oldest change; see Updates.
+func WithUpdates(buffer int) Option {
+	return func(rc *RateChecker) {
+		rc.updates = make(chan RateEvent, max(buffer, 0))
+	}
+}
+
+// WithDiscordWebhook posts every event as an embed to the Discord webhook url.
+func WithDiscordWebhook(url string) Option {
+	return func(rc *RateChecker) {
+		rc.discordURLs = append(rc.discordURLs, url)
+	}
+}
+
+// WithSlackWebhook posts every event to the Slack Incoming Webhook url.
+func WithSlackWebhook(url string) Option {
+	return func(rc *RateChecker) {
+		rc.slackURLs = append(rc.slackURLs, url)
+	}
+}
+
+// WithEmail emails every event to the recipients of cfg over SMTP.
+func WithEmail(cfg EmailConfig) Option {
+	return func(rc *RateChecker) {
+		rc.emailConfigs = append(rc.emailConfigs, cfg)
+	}
+}
+
+// WithAlertRules alerts whenever a rate crosses one of the rules' levels.
+func WithAlertRules(rules ...AlertRule) Option {
+	return func(rc *RateChecker) {
+		rc.rules = append(rc.rules, rules...)
+	}
+}
+
+// WithChangeNotifications turns the regular message on every rate change on or off.
+// Turning it off together with WithAlertRules only notifies when a level is crossed.
+func WithChangeNotifications(enabled bool) Option {
+	return func(rc *RateChecker) {
+		rc.changeNotifications = enabled
+	}
+}
+
+// WithBatchedMessages sends the rate changes of all currencies found by one check as a
+// single message, one line per currency, instead of one message per currency.
+func WithBatchedMessages(enabled bool) Option {
+	return func(rc *RateChecker) {
+		rc.batchChanges = enabled
+	}
+}
+
+// WithStartupMessage sends a "started, current rates are ..." message after the first
+// successful check, so readers know the checker restarted. The first rates then aren't
+// sent again as a regular change message.
+func WithStartupMessage(enabled bool) Option {
+	return func(rc *RateChecker) {
+		rc.announceStartup = enabled
+	}
+}
+
+// WithQuietHours holds back rate change messages and alerts during the daily window q, e.g.
+// overnight. Rates are still tracked and stored, and a broken scraper is still alerted
+// about right away. See QuietHours.
+func WithQuietHours(q QuietHours) Option {
+	return func(rc *RateChecker) {
+		rc.quietHours = &q
+	}
+}
+
+// WithHeartbeat sends the current rates every d, labeled as a heartbeat, even when they
+// didn't change, so readers can tell the checker is alive. It follows the checks, so it's
+// sent on the first check once d has passed, and not during quiet hours.
+func WithHeartbeat(d time.Duration) Option {
+	return func(rc *RateChecker) {
+		rc.heartbeat = d
+	}
+}
+
+// WithDedupWindow doesn't send a buy/sell pair again if the same pair was already
+// sent within d, even when the rate flickers back and forth between two values.
+func WithDedupWindow(d time.Duration) Option {
+	return func(rc *RateChecker) {
+		rc.dedupWindow = d
+	}
+}
+
+// WithUpdatedSelector sets the CSS selector of the element holding the page's
+// "last updated" time. When it can't be found, the fetch time is used instead.
+func WithUpdatedSelector(selector string) Option {
+	return func(rc *RateChecker) {
+		rc.updatedSelector = selector
+	}
+}
+
+// WithSelectors overrides the CSS selectors used to find the rate table, e.g. to scrape
+// another table of the site or to cope with a markup change until the scraper is fixed.
+// Empty fields keep their defaults.
+func WithSelectors(sel Selectors) Option {
+	return func(rc *RateChecker) {
+		rc.selectors = sel
+	}
+}
+
+// WithPrecision sets the number of decimals parsed values are rounded to before they're
+// compared, stored and encoded to JSON. Defaults to 4, matching the message format.
+func WithPrecision(decimals int) Option {
+	return func(rc *RateChecker) {
+		rc.precision = decimals
+	}
+}
+
+// WithTolerance sets how far apart buy or sell values may be and still count as unchanged,
+// so float noise like 2.70 against 2.7000000001 doesn't send a message. Defaults to 1e-6.
+func WithTolerance(eps float64) Option {
+	return func(rc *RateChecker) {
+		rc.tolerance = eps
+	}
+}
+
+// WithCycleTimeout bounds how long a single check started by Run may take, fetching and
+// notifying included. Defaults to the polling interval.
+func WithCycleTimeout(d time.Duration) Option {
+	return func(rc *RateChecker) {
+		rc.cycleTimeout = d
+	}
+}
+
+// WithSkipWeekdays makes Run skip checks on the given weekdays in the checker's timezone,
+// e.g. time.Saturday and time.Sunday, when rates don't move anyway. Checks resume on the
+// next other day.
+func WithSkipWeekdays(days ...time.Weekday) Option {
+	return func(rc *RateChecker) {
+		for _, d := range days {
+			rc.skipWeekdays[d] = true
+		}
+	}
+}
+
+// WithHolidays makes Run skip checks on the given dates, written as "2006-01-02" in the
+// checker's timezone.
+func WithHolidays(dates ...string) Option {
+	return func(rc *RateChecker) {
+		rc.holidayDates = append(rc.holidayDates, dates...)
+	}
+}
+
+// WithShutdownGrace sets how long Run lets an in-flight check finish after its context
+// is canceled before canceling the check too. Defaults to 15 seconds.
+func WithShutdownGrace(d time.Duration) Option {
+	return func(rc *RateChecker) {
+		rc.shutdownGrace = d
+	}
+}
+
+// WithSource adds another rate source next to rico.ge. With more than one source the
+// best buy and sell offers across them are reported whenever they change.
+func WithSource(s Source) Option {
+	return func(rc *RateChecker) {
+		rc.sources = append(rc.sources, s)
+	}
+}
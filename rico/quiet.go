@@ -0,0 +1,109 @@
+package rico
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// QuietHours is a daily window, in the checker's timezone, during which messages are held
+// back: rate changes, heartbeats and all alerts except the broken scraper alert. Alerts
+// are sent on the first check after the window ends. Start and End are "15:04" clock
+// times; a window with Start after End crosses midnight, e.g. 22:00 to 07:00.
+type QuietHours struct {
+	Start string `json:"start" yaml:"start"`
+	End   string `json:"end" yaml:"end"`
+	// CatchUp sends the rates that changed during the window in one message once it ends.
+	CatchUp bool `json:"catch_up" yaml:"catch_up"`
+}
+
+// window returns Start and End as offsets from midnight.
+func (q QuietHours) window() (start, end time.Duration, err error) {
+	if start, err = clockTime(q.Start); err != nil {
+		return 0, 0, fmt.Errorf("quiet hours start: %w", err)
+	}
+	if end, err = clockTime(q.End); err != nil {
+		return 0, 0, fmt.Errorf("quiet hours end: %w", err)
+	}
+	if start == end {
+		return 0, 0, fmt.Errorf("quiet hours start and end are both %s", q.Start)
+	}
+	return start, end, nil
+}
+
+// clockTime parses a "15:04" time of day into its offset from midnight.
+func clockTime(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a HH:MM time", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// quiet reports whether now falls into the quiet hours.
+func (rc *RateChecker) quiet(now time.Time) bool {
+	if rc.quietStart == rc.quietEnd {
+		return false
+	}
+	t := now.In(rc.location)
+	offset := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	if rc.quietStart < rc.quietEnd {
+		return offset >= rc.quietStart && offset < rc.quietEnd
+	}
+	// crosses midnight
+	return offset >= rc.quietStart || offset < rc.quietEnd
+}
+
+// holdChange keeps a rate change that wasn't sent because of the quiet hours. Later
+// changes of the same currency replace its rate but keep the rate from before the window,
+// so the catch-up message shows how much it moved overall.
+func (rc *RateChecker) holdChange(ev Event) {
+	if held, ok := rc.held[ev.Rate.Currency]; ok {
+		ev.Previous = held.Previous
+	}
+	rc.held[ev.Rate.Currency] = ev
+}
+
+// sendCatchUp sends the changes held back during the quiet hours as one message, if the
+// quiet hours have catch-up enabled, and forgets them.
+func (rc *RateChecker) sendCatchUp(ctx context.Context, now time.Time) error {
+	if len(rc.held) == 0 {
+		return nil
+	}
+	var changes []Event
+	for _, currency := range rc.currencies {
+		if ev, ok := rc.held[currency]; ok {
+			changes = append(changes, ev)
+		}
+	}
+	clear(rc.held)
+	if !rc.quietHours.CatchUp {
+		return nil
+	}
+	ev := Event{Kind: EventRateChanges, Changes: changes, Text: rc.formatBatch(changes, now), Time: now}
+	return rc.notify(ctx, ev)
+}
+
+// notifyUnlessQuiet notifies about ev, or holds it back until the quiet hours end.
+func (rc *RateChecker) notifyUnlessQuiet(ctx context.Context, ev Event) error {
+	if rc.quiet(rc.clock.Now()) {
+		rc.heldAlerts = append(rc.heldAlerts, ev)
+		return nil
+	}
+	return rc.notify(ctx, ev)
+}
+
+// sendHeldAlerts sends the alerts held back during the quiet hours, in the order they
+// were raised, and forgets them.
+func (rc *RateChecker) sendHeldAlerts(ctx context.Context) error {
+	held := rc.heldAlerts
+	rc.heldAlerts = nil
+	var errs []error
+	for _, ev := range held {
+		if err := rc.notify(ctx, ev); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
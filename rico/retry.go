@@ -0,0 +1,188 @@
+package rico
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"strings"
+	"time"
+)
+
+const (
+	defaultRetryAttempts = 3
+	defaultRetryDelay    = 1 * time.Second
+	defaultTimeout       = 10 * time.Second // per request, set a reasonable timeout
+	defaultUserAgent     = "rico_parser_go (+https://github.com/lukamindo/rico_parser_go)"
+	defaultLanguage      = "ka"
+	defaultMaxBodySize   = 5 << 20 // rico.ge pages are a few hundred KB
+)
+
+// ErrBodyTooLarge is returned when a page is bigger than the configured maximum body size.
+var ErrBodyTooLarge = errors.New("response body too large")
+
+// retryBudgetKey is the context key of the time after which a cycle doesn't retry anymore.
+type retryBudgetKey struct{}
+
+// withRetryBudget starts a cycle's retry budget, if one is configured. Requests made with
+// the returned context stop retrying once the budget is used up.
+func (rc *RateChecker) withRetryBudget(ctx context.Context) context.Context {
+	if rc.retryBudget <= 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, retryBudgetKey{}, time.Now().Add(rc.retryBudget))
+}
+
+// getWithRetry performs a GET request to url, retrying connection errors and 5xx
+// responses with exponential backoff, randomized by the configured jitter. Any other response is returned to the caller as is.
+// No retry is started that would end after the cycle's retry budget.
+func (rc *RateChecker) getWithRetry(ctx context.Context, url string) (*http.Response, error) {
+	budgetEnd, hasBudget := ctx.Value(retryBudgetKey{}).(time.Time)
+	var lastErr error
+	for attempt := 0; attempt < rc.retryAttempts; attempt++ {
+		if attempt > 0 {
+			delay := rc.jittered(rc.retryDelay << (attempt - 1))
+			if hasBudget && time.Now().Add(delay).After(budgetEnd) {
+				return nil, fmt.Errorf("retry budget of %s used up after %d attempts: %w", rc.retryBudget, attempt, lastErr)
+			}
+			rc.logger.Warn("retrying request", "url", url, "delay", delay, "attempt", attempt+1, "max_attempts", rc.retryAttempts, "error", lastErr)
+			if err := sleep(ctx, delay); err != nil {
+				return nil, fmt.Errorf("waiting to retry: %w (last error: %v)", err, lastErr)
+			}
+		}
+
+		// every attempt gets its own deadline, which lasts until the body is closed
+		attemptCtx, cancel := context.WithTimeout(ctx, rc.fetchTimeout)
+		req, err := http.NewRequestWithContext(attemptCtx, http.MethodGet, url, nil)
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("creating request: %w", err)
+		}
+		req.Header.Set("User-Agent", rc.userAgent)
+		req.Header.Set("Accept-Language", pageLanguage(url))
+
+		start := time.Now()
+		resp, err := rc.client.Do(req)
+		rc.metrics.observeLatency(time.Since(start))
+		if err != nil {
+			cancel()
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("fetching URL: %w", err)
+			}
+			lastErr = fmt.Errorf("fetching URL: %w", err)
+			continue
+		}
+
+		if resp.StatusCode >= http.StatusInternalServerError {
+			resp.Body.Close()
+			cancel()
+			lastErr = fmt.Errorf("received non-200 response code: %d", resp.StatusCode)
+			continue
+		}
+		resp.Body = cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+		return resp, nil
+	}
+	return nil, fmt.Errorf("giving up after %d attempts: %w", rc.retryAttempts, lastErr)
+}
+
+// withTransport returns a copy of c whose transport is changed by configure. Only clients
+// using an *http.Transport, as the default one does, can be configured.
+func withTransport(c *http.Client, configure func(*http.Transport)) (*http.Client, error) {
+	var transport *http.Transport
+	switch t := c.Transport.(type) {
+	case nil:
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		transport = t.Clone()
+	default:
+		return nil, fmt.Errorf("can't configure an HTTP client with a %T transport", c.Transport)
+	}
+	configure(transport)
+
+	configured := *c
+	configured.Transport = transport
+	return &configured, nil
+}
+
+// withProxy returns a copy of c sending its requests through the proxy at proxyURL.
+func withProxy(c *http.Client, proxyURL string) (*http.Client, error) {
+	u, err := neturl.Parse(proxyURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL %q", proxyURL)
+	}
+	c, err = withTransport(c, func(t *http.Transport) { t.Proxy = http.ProxyURL(u) })
+	if err != nil {
+		return nil, fmt.Errorf("setting proxy: %w", err)
+	}
+	return c, nil
+}
+
+// withInsecureTLS returns a copy of c that accepts any TLS certificate.
+func withInsecureTLS(c *http.Client) (*http.Client, error) {
+	c, err := withTransport(c, func(t *http.Transport) {
+		if t.TLSClientConfig == nil {
+			t.TLSClientConfig = &tls.Config{}
+		}
+		t.TLSClientConfig.InsecureSkipVerify = true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("disabling TLS verification: %w", err)
+	}
+	return c, nil
+}
+
+// pageLanguage derives the locale of a rico.ge page from its path, e.g. "ka" for
+// https://www.rico.ge/ka, falling back to Georgian.
+func pageLanguage(pageURL string) string {
+	u, err := neturl.Parse(pageURL)
+	if err != nil {
+		return defaultLanguage
+	}
+	lang, _, _ := strings.Cut(strings.TrimPrefix(u.Path, "/"), "/")
+	if len(lang) != 2 {
+		return defaultLanguage
+	}
+	return strings.ToLower(lang)
+}
+
+// cancelOnClose releases the request context once the response body is closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// readBody reads all of r, failing with ErrBodyTooLarge instead of truncating once more
+// than limit bytes arrive. The read is bound to the request context, so it is cut off when
+// the fetch timeout expires.
+func readBody(r io.Reader, limit int64) ([]byte, error) {
+	b, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("reading body: %w", err)
+	}
+	if int64(len(b)) > limit {
+		return nil, fmt.Errorf("%w: over %d bytes", ErrBodyTooLarge, limit)
+	}
+	return b, nil
+}
+
+// sleep waits for d or until ctx is done, whichever comes first.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
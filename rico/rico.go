@@ -1,167 +1,1363 @@
 package rico
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
+	"maps"
+	"math"
+	"math/rand"
 	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"runtime/debug"
 	"strconv"
 	"strings"
+	"sync"
+	"text/template"
 	"time"
+	"unicode/utf8"
 
 	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html/charset"
 )
 
 const (
-	url        = "https://www.rico.ge/ka"
-	timezone   = "Asia/Tbilisi"
-	timeFormat = "Jan 2 15:04:05"
+	defaultURL      = "https://www.rico.ge/ka"
+	timezone        = "Asia/Tbilisi"   // rico.ge's zone, and the default for messages
+	timeFormat      = "Jan 2 15:04:05" // default layout of message timestamps
+	defaultCurrency = "USD"
+	DefaultInterval = 1 * time.Minute // check rate every 1 minute
+
+	defaultUpdatedSelector = ".update-date" // element showing when the page's rates were last updated
+	defaultShutdownGrace   = 15 * time.Second
+	defaultPrecision       = 4 // decimals shown in messages
+
+	branchParam = "branch" // query parameter selecting a rico.ge branch
+)
+
+var (
+	// ErrInvalidBotToken is returned by NewRateChecker for an empty or malformed bot token.
+	ErrInvalidBotToken = errors.New("invalid bot token")
+	// ErrInvalidChannelID is returned by NewRateChecker for a missing or malformed channel ID.
+	ErrInvalidChannelID = errors.New("invalid channel ID")
+	// ErrRateTableNotFound is returned when the page has no rate rows, which means
+	// the site layout changed and the scraper needs fixing.
+	ErrRateTableNotFound = errors.New("rate table not found")
+	// ErrZeroRate is returned when a rate on the page parses as zero, which is usually a
+	// transient glitch of the page rather than a real rate.
+	ErrZeroRate = errors.New("rate is zero")
+
+	botTokenPattern  = regexp.MustCompile(`^\d+:[A-Za-z0-9_-]+$`)
+	channelIDPattern = regexp.MustCompile(`^(-?\d+|@[A-Za-z][A-Za-z0-9_]{3,})$`)
+	// matches update times like "14.10.2026 12:05" or "14.10.2026 12:05:30"
+	updatedPattern = regexp.MustCompile(`(\d{2})\.(\d{2})\.(\d{4})\s+(\d{1,2}):(\d{2})(?::(\d{2}))?`)
 )
 
-type USDRate struct {
-	Buy  float64
-	Sell float64
+// Rate holds the buy and sell values of a single currency against GEL. The checker rounds
+// the values of every rate it fetches to its precision, see WithPrecision, so they encode
+// to JSON as they're stored and compared, without float noise like 2.6999999.
+type Rate struct {
+	Currency string    `json:"currency"`
+	Buy      float64   `json:"buy"`
+	Sell     float64   `json:"sell"`
+	Source   string    `json:"source,omitempty"` // name of the source the rate came from
+	Updated  time.Time `json:"time"`             // when the page says the rate was last updated, or when it was fetched
+
+	// non-cash (transfer) rates, zero when the page only lists cash rates
+	TransferBuy  float64 `json:"transfer_buy,omitempty"`
+	TransferSell float64 `json:"transfer_sell,omitempty"`
+}
+
+// hasTransfer reports whether r carries transfer rates next to the cash ones.
+func (r Rate) hasTransfer() bool {
+	return r.TransferBuy != 0 || r.TransferSell != 0
+}
+
+// Invert returns the rate expressed per 1 GEL instead of per 1 unit of the currency.
+// Missing (zero) values stay zero rather than dividing by zero.
+func (r Rate) Invert() Rate {
+	inv := r
+	if r.Buy != 0 {
+		inv.Buy = 1 / r.Buy
+	}
+	if r.Sell != 0 {
+		inv.Sell = 1 / r.Sell
+	}
+	if r.TransferBuy != 0 {
+		inv.TransferBuy = 1 / r.TransferBuy
+	}
+	if r.TransferSell != 0 {
+		inv.TransferSell = 1 / r.TransferSell
+	}
+	return inv
+}
+
+// rounded returns r with its buy, sell and transfer values rounded to the given number of
+// decimal places.
+func (r Rate) rounded(places int) Rate {
+	r.Buy, r.Sell = round(r.Buy, places), round(r.Sell, places)
+	r.TransferBuy, r.TransferSell = round(r.TransferBuy, places), round(r.TransferSell, places)
+	return r
+}
+
+// round rounds v to the given number of decimal places.
+func round(v float64, places int) float64 {
+	scale := math.Pow(10, float64(places))
+	return math.Round(v*scale) / scale
+}
+
+// defaultTolerance is how far apart two values may be and still count as the same rate.
+const defaultTolerance = 1e-6
+
+// ratesEqual reports whether a and b have the same buy, sell and transfer values, within
+// eps of each other, so float noise from parsing doesn't count as a change.
+func ratesEqual(a, b Rate, eps float64) bool {
+	return nearlyEqual(a.Buy, b.Buy, eps) && nearlyEqual(a.Sell, b.Sell, eps) &&
+		nearlyEqual(a.TransferBuy, b.TransferBuy, eps) && nearlyEqual(a.TransferSell, b.TransferSell, eps)
+}
+
+// nearlyEqual reports whether a and b are at most eps apart.
+func nearlyEqual(a, b, eps float64) bool {
+	return math.Abs(a-b) <= eps
+}
+
+// Spread returns the difference between the sell and buy values.
+// It is zero when either value is missing.
+func (r Rate) Spread() float64 {
+	if r.Buy == 0 || r.Sell == 0 {
+		return 0
+	}
+	return r.Sell - r.Buy
+}
+
+// RateChange is the outcome of one check for one currency.
+type RateChange struct {
+	Old     Rate // rate compared against; zero on the first check
+	New     Rate // rate just fetched
+	Changed bool // New replaced Old as the stored rate
 }
 
 type RateChecker struct {
-	USDRate   USDRate
-	botToken  string
-	channelID string
-	client    *http.Client
-	location  *time.Location
+	mu          sync.RWMutex    // guards rates, lastSuccess, lastChange, failures and recent against concurrent readers
+	rates       map[string]Rate // last seen rate per currency code
+	lastSuccess time.Time       // time of the last successful fetch
+	lastChange  time.Time       // time a stored rate last changed
+	failures    int             // fetches failed in a row
+	recent      *ring           // every observed rate, for RecentRates
+	historySize int
+
+	extremes       map[string]Extremes // highest and lowest rates seen per currency
+	extremesLoaded bool                // whether the store's extremes were merged in
+
+	statePath string // JSON file the last rates are kept in across restarts, empty keeps none
+
+	cacheTTL time.Duration // how long FetchCurrentRate reuses a fetch, defaults to the interval
+	cacheMu  sync.Mutex    // guards cached and cachedAt
+	cached   map[string]Rate
+	cachedAt time.Time
+
+	currencies   []string
+	client       *http.Client // its default transport honors HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+	proxyURL     string       // proxy every request goes through, overriding the environment
+	insecureTLS  bool         // accept any TLS certificate, for tests only
+	clock        Clock
+	timezone     string         // name of location, set with WithTimezone
+	location     *time.Location // zone messages and day boundaries use
+	pageLocation *time.Location // zone of the times shown on rico.ge
+	timeFormat   string         // layout of message timestamps
+	interval     time.Duration
+	url          string
+	branch       string // rico.ge branch whose rates are scraped, empty for the default page
+
+	shutdownGrace time.Duration // how long Run waits for an in-flight check on shutdown
+	cycleTimeout  time.Duration // deadline of one check in Run, defaults to the interval
+
+	heartbeat     time.Duration // send the current rates this often even when unchanged, zero disables it
+	lastHeartbeat time.Time
+
+	skipWeekdays map[time.Weekday]bool // weekdays Run doesn't check on
+	holidayDates []string              // dates Run doesn't check on, as given to WithHolidays
+	holidays     map[string]bool       // holidayDates once parsed
+
+	retryAttempts int
+	retryDelay    time.Duration
+	retryBudget   time.Duration // time a cycle may spend before it stops retrying, zero is unlimited
+	jitter        float64       // fraction retry delays and the first check are randomized by
+	randMu        sync.Mutex    // guards rand
+	rand          *rand.Rand    // source of the jitter, seeded from the time when nil
+	zeroRetries   int           // refetches when the page shows a zero rate
+	userAgent     string
+	fetchTimeout  time.Duration // deadline of one scrape request
+	maxBodySize   int64         // largest page accepted, in bytes
+	dumpSize      int           // bytes of a page that failed to parse to keep, zero keeps none
+	dumpDir       string        // where to write those bytes, empty logs them
+	sendTimeout   time.Duration // deadline of one notification request
+	authLimit     int           // Telegram auth failures in a row before sends stop
+
+	updatedSelector string    // element holding the page's "last updated" time
+	selectors       Selectors // elements of the rate table
+	precision       int       // decimals parsed values are rounded to
+	tolerance       float64   // values closer than this count as equal
+
+	sources   []Source            // sources[0] is the primary one driving change notifications
+	best      map[string]BestRate // last reported best offers per currency
+	arbitrage map[string]bool     // arbitrage opportunities open in the previous check
+
+	store Store // optional, nil keeps history in memory only
+
+	// minimum move of buy or sell that triggers a message, zero disables the check
+	minChange        float64
+	minPercentChange float64
+
+	bigMove *BigMove // louder alert for a sharp move between two checks, nil disables it
+
+	observed map[string]Rate // rate of the previous check per currency, for level crossings
+	rules    []AlertRule
+
+	changeNotifications bool // notify about every significant rate change
+	announceStartup     bool // send the first rates as a startup message instead of a change
+	batchChanges        bool // send the changes of one check as a single message
+	announced           bool
+
+	dedupWindow time.Duration         // don't resend a rate sent within this window, zero disables it
+	sent        map[string][]sentRate // rates sent within dedupWindow per currency
+
+	spreadAlert   float64         // alert when the spread exceeds this, zero disables it
+	spreadAlerted map[string]bool // currencies whose spread is currently above spreadAlert
+
+	logger  *slog.Logger
+	metrics *metrics // nil when metrics are disabled
+
+	healthAddr           string
+	healthStaleIntervals int
+	serverMu             sync.Mutex   // guards server
+	server               *http.Server // embedded HTTP server while Run serves it
+
+	daily        map[string]DailySummary // running summary of the current day per currency
+	dailySummary bool                    // send each finished day's summary
+
+	alertOnMissingTable bool // alert when the rate table disappears
+	tableMissingAlerted bool
+
+	emaShort, emaLong int                 // periods of the sell EMAs, zero disables crossover alerts
+	ema               map[string]emaState // sell EMAs per currency
+
+	frozenAfter   time.Duration        // alert when a rate stays unchanged this long, zero disables it
+	lastChanged   map[string]time.Time // when each currency's rate last moved between fetches
+	frozenAlerted map[string]bool
+
+	updatesMu     sync.Mutex     // guards sending on updates against closing it
+	updates       chan RateEvent // detected rate changes, nil without WithUpdates
+	updatesClosed bool
+
+	quietHours           *QuietHours      // nil sends messages at any time
+	quietStart, quietEnd time.Duration    // quiet hours as offsets from midnight
+	held                 map[string]Event // rate changes held back during the quiet hours
+	heldAlerts           []Event          // alerts held back during the quiet hours
+
+	onChange     []func(old, new Rate) // called on every detected rate change
+	notifiers    []Notifier
+	webhookURLs  []string
+	discordURLs  []string
+	slackURLs    []string
+	emailConfigs []EmailConfig
+	telegram     *telegramNotifier // nil when no Telegram channel is configured
+	backupTokens []string          // bot tokens tried in order when Telegram refuses the current one
+	commands     bool              // answer commands like /rate posted to the Telegram chats
+
+	subsMu        sync.RWMutex        // guards subscriptions, which commands update while checks read them
+	subscriptions map[string][]string // currencies per chat ID set with /subscribe, empty meaning all
+
+	dryRun bool // log messages instead of sending them
+
+	parseMode    ParseMode
+	inverted     bool // show rates per 1 GEL in messages
+	sourceLink   bool // end plain text messages with the page URL
+	templateText string
+	decimals     int    // decimal places of values shown in messages
+	decimalSep   string // decimal separator of values shown in messages
+	template     *template.Template
 }
 
 // NewRateChecker creates a new instance of RateChecker with provided configuration.
-func NewRateChecker(botToken, channelID string) (*RateChecker, error) {
-	loc, err := time.LoadLocation(timezone)
+// Messages are broadcast to every channel in channelIDs. Telegram may be left
+// unconfigured (empty botToken and channelIDs) when another notifier is set up.
+// currencies lists the codes to watch (e.g. "USD", "EUR"); USD is used when empty.
+func NewRateChecker(botToken string, channelIDs []string, currencies []string, opts ...Option) (*RateChecker, error) {
+	rc, err := newRateChecker(currencies, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, u := range rc.webhookURLs {
+		rc.notifiers = append(rc.notifiers, &webhookNotifier{client: rc.client, url: u, timeout: rc.sendTimeout})
+	}
+	for _, u := range rc.discordURLs {
+		rc.notifiers = append(rc.notifiers, &discordNotifier{client: rc.client, url: u, timeout: rc.sendTimeout, numbers: rc.numbers()})
+	}
+	for _, u := range rc.slackURLs {
+		rc.notifiers = append(rc.notifiers, &slackNotifier{client: rc.client, url: u, timeout: rc.sendTimeout, numbers: rc.numbers()})
+	}
+	for _, cfg := range rc.emailConfigs {
+		if err := cfg.validate(); err != nil {
+			return nil, err
+		}
+		rc.notifiers = append(rc.notifiers, &emailNotifier{cfg: cfg, timeout: rc.sendTimeout, location: rc.location, timeFormat: rc.timeFormat, numbers: rc.numbers()})
+	}
+	// Telegram stays mandatory unless another notifier is configured.
+	if botToken != "" || len(channelIDs) > 0 || len(rc.notifiers) == 0 {
+		if err := validateTelegram(botToken, channelIDs); err != nil {
+			return nil, err
+		}
+		for i, token := range rc.backupTokens {
+			if !botTokenPattern.MatchString(token) {
+				return nil, fmt.Errorf("%w: backup token %d: expected <bot id>:<secret>", ErrInvalidBotToken, i+1)
+			}
+		}
+		rc.telegram = &telegramNotifier{
+			client:     rc.client,
+			botTokens:  append([]string{botToken}, rc.backupTokens...),
+			channelIDs: channelIDs,
+			parseMode:  rc.parseMode,
+			timeout:    rc.sendTimeout,
+			attempts:   rc.retryAttempts,
+			authLimit:  rc.authLimit,
+			revoked:    make(chan struct{}),
+			logger:     rc.logger,
+			render:     rc.eventText,
+		}
+		if rc.bigMove != nil {
+			rc.telegram.bigMoveIDs = rc.bigMove.ChannelIDs
+		}
+		rc.notifiers = append([]Notifier{rc.telegram}, rc.notifiers...)
+	}
+	if len(rc.backupTokens) > 0 && rc.telegram == nil {
+		return nil, errors.New("backup bot tokens need a Telegram bot token and channel")
+	}
+	if rc.bigMove != nil && len(rc.bigMove.ChannelIDs) > 0 && rc.telegram == nil {
+		return nil, errors.New("big move chats need a Telegram bot token and channel")
+	}
+	if rc.commands {
+		if rc.telegram == nil {
+			return nil, errors.New("telegram commands need a Telegram bot token and channel")
+		}
+		rc.subscriptions = make(map[string][]string)
+		rc.telegram.recipients = rc.recipients
+		rc.telegram.narrow = rc.narrow
+	}
+	return rc, nil
+}
+
+// newRateChecker creates a RateChecker with opts applied and validated, but no notifiers.
+func newRateChecker(currencies []string, opts ...Option) (*RateChecker, error) {
+	pageLoc, err := time.LoadLocation(timezone)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load timezone: %w", err)
 	}
 
+	if len(currencies) == 0 {
+		currencies = []string{defaultCurrency}
+	}
+	watched := make([]string, 0, len(currencies))
+	for _, c := range currencies {
+		watched = append(watched, normalizeCurrency(c))
+	}
+
 	rc := &RateChecker{
-		USDRate:   USDRate{},
-		botToken:  botToken,
-		channelID: channelID,
-		client: &http.Client{
-			Timeout: 10 * time.Second, // set a reasonable timeout
-		},
-		location: loc,
+		rates:        make(map[string]Rate),
+		currencies:   watched,
+		client:       &http.Client{},
+		clock:        realClock{},
+		location:     pageLoc,
+		pageLocation: pageLoc,
+		timeFormat:   timeFormat,
+		interval:     DefaultInterval,
+		url:          defaultURL,
+
+		shutdownGrace: defaultShutdownGrace,
+		skipWeekdays:  make(map[time.Weekday]bool),
+		historySize:   defaultHistorySize,
+
+		retryAttempts: defaultRetryAttempts,
+		retryDelay:    defaultRetryDelay,
+		userAgent:     defaultUserAgent,
+		fetchTimeout:  defaultTimeout,
+		maxBodySize:   defaultMaxBodySize,
+		sendTimeout:   defaultTimeout,
+		authLimit:     defaultAuthFailureLimit,
+
+		updatedSelector: defaultUpdatedSelector,
+		precision:       defaultPrecision,
+		decimals:        defaultPrecision,
+		tolerance:       defaultTolerance,
+
+		spreadAlerted: make(map[string]bool),
+		lastChanged:   make(map[string]time.Time),
+		frozenAlerted: make(map[string]bool),
+		held:          make(map[string]Event),
+		observed:      make(map[string]Rate),
+		best:          make(map[string]BestRate),
+		sent:          make(map[string][]sentRate),
+		daily:         make(map[string]DailySummary),
+		extremes:      make(map[string]Extremes),
+		ema:           make(map[string]emaState),
+		logger:        slog.New(slog.NewTextHandler(os.Stderr, nil)),
+
+		changeNotifications: true,
+	}
+	for _, opt := range opts {
+		opt(rc)
+	}
+
+	if rc.timezone != "" {
+		if rc.location, err = time.LoadLocation(rc.timezone); err != nil {
+			return nil, fmt.Errorf("loading timezone %q: %w", rc.timezone, err)
+		}
+	}
+	if err := validateTimeFormat(rc.timeFormat); err != nil {
+		return nil, err
+	}
+	if rc.url == "" {
+		rc.url = defaultURL
+	}
+	if rc.userAgent == "" {
+		rc.userAgent = defaultUserAgent
+	}
+	if rc.branch != "" {
+		if rc.url, err = branchURL(rc.url, rc.branch); err != nil {
+			return nil, err
+		}
+	}
+	rc.selectors = rc.selectors.withDefaults()
+	if rc.proxyURL != "" {
+		if rc.client, err = withProxy(rc.client, rc.proxyURL); err != nil {
+			return nil, err
+		}
+	}
+	if rc.insecureTLS {
+		if rc.client, err = withInsecureTLS(rc.client); err != nil {
+			return nil, err
+		}
+		rc.logger.Warn("TLS certificate verification is disabled, this is only meant for tests")
+	}
+	rc.sources = append([]Source{&ricoSource{rc: rc, url: rc.url}}, rc.sources...)
+	if rc.interval <= 0 {
+		return nil, fmt.Errorf("interval must be positive, got %s", rc.interval)
+	}
+	if rc.cycleTimeout < 0 {
+		return nil, fmt.Errorf("cycle timeout must not be negative, got %s", rc.cycleTimeout)
+	}
+	if rc.cycleTimeout == 0 {
+		rc.cycleTimeout = rc.interval
+	}
+	if rc.cacheTTL == 0 {
+		rc.cacheTTL = rc.interval
+	}
+	if rc.shutdownGrace < 0 {
+		return nil, fmt.Errorf("shutdown grace period must not be negative, got %s", rc.shutdownGrace)
+	}
+	if rc.retryAttempts < 1 {
+		return nil, fmt.Errorf("retry attempts must be at least 1, got %d", rc.retryAttempts)
+	}
+	if rc.zeroRetries < 0 {
+		return nil, fmt.Errorf("zero rate retries must not be negative, got %d", rc.zeroRetries)
+	}
+	if rc.retryDelay < 0 {
+		return nil, fmt.Errorf("retry delay must not be negative, got %s", rc.retryDelay)
+	}
+	if rc.jitter < 0 || rc.jitter > 1 {
+		return nil, fmt.Errorf("jitter must be between 0 and 1, got %g", rc.jitter)
+	}
+	if rc.retryBudget < 0 {
+		return nil, fmt.Errorf("retry budget must not be negative, got %s", rc.retryBudget)
+	}
+	if !rc.parseMode.valid() {
+		return nil, fmt.Errorf("unsupported parse mode %q", rc.parseMode)
+	}
+	if rc.healthStaleIntervals <= 0 {
+		rc.healthStaleIntervals = defaultHealthStaleIntervals
+	}
+	if rc.decimals < 0 {
+		return nil, fmt.Errorf("decimal places must not be negative, got %d", rc.decimals)
+	}
+	if rc.decimalSep == "" {
+		rc.decimalSep = "."
+	}
+	if rc.template, err = parseTemplate(rc.templateText, rc.parseMode, rc.number); err != nil {
+		return nil, fmt.Errorf("parsing message template: %w", err)
+	}
+	if rc.fetchTimeout <= 0 || rc.sendTimeout <= 0 {
+		return nil, errors.New("timeouts must be positive")
+	}
+	if rc.dumpSize < 0 {
+		return nil, fmt.Errorf("page dump size must not be negative, got %d", rc.dumpSize)
+	}
+	if rc.maxBodySize <= 0 {
+		return nil, fmt.Errorf("max body size must be positive, got %d", rc.maxBodySize)
+	}
+	for i, rule := range rc.rules {
+		rc.rules[i].Currency = normalizeCurrency(rule.Currency)
+		if !rc.watches(rc.rules[i].Currency) {
+			return nil, fmt.Errorf("alert rule for %q, which isn't a watched currency", rule.Currency)
+		}
+	}
+	if rc.historySize < 0 {
+		return nil, fmt.Errorf("history size must not be negative, got %d", rc.historySize)
+	}
+	rc.recent = newRing(rc.historySize)
+	if rc.precision < 0 {
+		return nil, fmt.Errorf("precision must not be negative, got %d", rc.precision)
+	}
+	if rc.tolerance < 0 {
+		return nil, fmt.Errorf("tolerance must not be negative, got %g", rc.tolerance)
+	}
+	if rc.dedupWindow < 0 {
+		return nil, fmt.Errorf("dedup window must not be negative, got %s", rc.dedupWindow)
+	}
+	if (rc.emaShort != 0 || rc.emaLong != 0) && (rc.emaShort < 1 || rc.emaShort >= rc.emaLong) {
+		return nil, fmt.Errorf("EMA periods must be positive with short < long, got %d and %d", rc.emaShort, rc.emaLong)
+	}
+	if rc.frozenAfter < 0 {
+		return nil, fmt.Errorf("frozen rate window must not be negative, got %s", rc.frozenAfter)
+	}
+	if rc.minChange < 0 || rc.minPercentChange < 0 || rc.spreadAlert < 0 {
+		return nil, errors.New("change thresholds must not be negative")
+	}
+	if rc.bigMove != nil {
+		if err := rc.bigMove.validate(); err != nil {
+			return nil, err
+		}
+	}
+	if rc.updates != nil && cap(rc.updates) < 1 {
+		// an unbuffered channel would have nowhere to keep a change for a consumer
+		return nil, errors.New("updates buffer must be at least 1")
+	}
+	if rc.heartbeat < 0 {
+		return nil, fmt.Errorf("heartbeat interval must not be negative, got %s", rc.heartbeat)
+	}
+	if rc.statePath != "" {
+		rc.loadState()
+	}
+	if rc.holidays, err = parseHolidays(rc.holidayDates); err != nil {
+		return nil, err
+	}
+	if rc.quietHours != nil {
+		if rc.quietStart, rc.quietEnd, err = rc.quietHours.window(); err != nil {
+			return nil, err
+		}
 	}
 	return rc, nil
 }
 
-// CheckForRateChange checks if the rate has changed, and if so, sends a Telegram message.
-func (rc *RateChecker) CheckForRateChange(ctx context.Context) {
-	usdRate, err := rc.fetchCurrentRate(ctx)
+// Run checks for a rate change immediately and then on every interval until ctx is canceled.
+// A check that is still in flight when ctx is canceled may finish within the shutdown grace
+// period, so a notification isn't cut off halfway; Run returns once it's done.
+func (rc *RateChecker) Run(ctx context.Context) {
+	rc.startServer()
+	defer rc.stopServer()
+	stopCommands := rc.startCommands(ctx)
+	defer stopCommands()
+
+	// checks run on a context that outlives ctx until the grace period is over
+	workCtx, cancelWork := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelWork()
+
+	if d := rc.startDelay(); d > 0 {
+		rc.logger.Info("delaying the first check", "delay", d)
+		if err := sleep(ctx, d); err != nil {
+			rc.logger.Info("context canceled, shutting down")
+			return
+		}
+	}
+	ticker := time.NewTicker(rc.interval)
+	defer ticker.Stop()
+
+	var skipped string // day checks were last skipped on, to log it only once
+	for {
+		if day, skip := rc.skipDay(rc.clock.Now()); skip {
+			if day != skipped {
+				rc.logger.Info("not checking rates on a skipped day", "day", day)
+				skipped = day
+			}
+		} else if !rc.runCheck(ctx, workCtx, cancelWork, ticker) {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			rc.logger.Info("context canceled, shutting down")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Close releases what the checker holds on to: it shuts down the embedded HTTP server
+// should Run still be serving it, closes the Updates channel and idle HTTP connections,
+// and closes the store if it has a Close method, as SQLiteStore does. It doesn't stop Run;
+// cancel its context for that. Changes detected after Close aren't delivered on Updates.
+func (rc *RateChecker) Close() error {
+	rc.stopServer()
+	rc.closeUpdates()
+	rc.client.CloseIdleConnections()
+	if c, ok := rc.store.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			return fmt.Errorf("closing store: %w", err)
+		}
+	}
+	return nil
+}
+
+// runCheck runs a single check on workCtx and waits for it. It returns false once ctx is
+// canceled, after the check drained.
+func (rc *RateChecker) runCheck(ctx, workCtx context.Context, cancelWork context.CancelFunc, ticker *time.Ticker) bool {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		rc.check(workCtx)
+	}()
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		rc.drain(done, cancelWork)
+		return false
+	}
+
+	// Checks never overlap; a tick that fired while the check was running is
+	// dropped instead of starting the next check right away.
+	select {
+	case <-ticker.C:
+		rc.logger.Warn("check took longer than the interval, skipping a tick", "interval", rc.interval)
+	default:
+	}
+	return true
+}
+
+// drain waits for the in-flight check to finish, canceling it once the grace period is over.
+func (rc *RateChecker) drain(done <-chan struct{}, cancel context.CancelFunc) {
+	rc.logger.Info("context canceled, waiting for the running check to finish", "grace", rc.shutdownGrace)
+
+	timer := time.NewTimer(rc.shutdownGrace)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+	case <-timer.C:
+		rc.logger.Warn("running check didn't finish within the grace period, canceling it")
+		cancel()
+		<-done
+	}
+	rc.logger.Info("shut down")
+}
+
+// check runs a single CheckForRateChange bounded by the cycle timeout and logs its error, if any,
+// or the panic it ran into.
+func (rc *RateChecker) check(ctx context.Context) {
+	// a bug hit by one odd page must not stop the monitoring for good
+	defer func() {
+		if r := recover(); r != nil {
+			rc.logger.Error("check panicked, continuing with the next one", "panic", r, "stack", string(debug.Stack()))
+		}
+	}()
+
+	// a hung fetch must not hold up the next tick indefinitely
+	cycleCtx, cancel := context.WithTimeout(ctx, rc.cycleTimeout)
+	defer cancel()
+
+	_, err := rc.CheckForRateChange(cycleCtx)
+	switch {
+	case err == nil:
+	case ctx.Err() == nil && errors.Is(cycleCtx.Err(), context.DeadlineExceeded):
+		rc.logger.Warn("check didn't finish within the cycle timeout, moving on", "timeout", rc.cycleTimeout, "error", err)
+	default:
+		rc.logger.Error("checking for rate change", "error", err)
+	}
+}
+
+// CheckForRateChange checks if any watched rate has changed, and if so, notifies about it.
+// It returns the outcome per watched currency, in the order they were given, along with
+// the fetch error or the joined send errors of every currency that failed to send. The
+// outcomes are nil when the fetch failed.
+func (rc *RateChecker) CheckForRateChange(ctx context.Context) ([]RateChange, error) {
+	ctx = rc.withRetryBudget(ctx)
+	// checks always scrape, refreshing the cache for FetchCurrentRate
+	rates, others, othersErr, err := rc.fetchAll(ctx)
+	rc.metrics.observeFetch(err)
 	if err != nil {
-		log.Printf("Error fetching current rate: %v\n", err)
-		return
+		rc.mu.Lock()
+		rc.failures++
+		rc.mu.Unlock()
+		if errors.Is(err, ErrRateTableNotFound) {
+			if alertErr := rc.alertTableMissing(ctx, err); alertErr != nil {
+				err = errors.Join(err, fmt.Errorf("sending scraper alert: %w", alertErr))
+			}
+		}
+		return nil, fmt.Errorf("fetching current rate: %w", err)
+	}
+	rc.tableMissingAlerted = false
+	rc.mu.Lock()
+	now := rc.clock.Now()
+	rc.lastSuccess = now
+	rc.failures = 0
+	rc.mu.Unlock()
+
+	errs := []error{othersErr}
+	if err := rc.compareSources(ctx, rates, others); err != nil {
+		errs = append(errs, err)
+	}
+	if err := rc.loadExtremes(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	quiet := rc.quiet(now)
+	if !quiet {
+		if err := rc.sendCatchUp(ctx, now); err != nil {
+			errs = append(errs, fmt.Errorf("sending quiet hours catch-up: %w", err))
+		}
+		if err := rc.sendHeldAlerts(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("sending alerts held during quiet hours: %w", err))
+		}
+	}
+	startup := rc.announceStartup && !rc.announced
+	var batch []Event
+	results := make([]RateChange, 0, len(rc.currencies))
+	changed := false
+
+	for _, currency := range rc.currencies {
+		rate := rates[currency]
+		rc.metrics.observeRate(rate)
+		rc.recordRecent(rate, now)
+		rc.recordExtremes(rate, now)
+
+		if finished := rc.recordDaily(rate, now); finished != nil && rc.dailySummary {
+			if err := rc.alert(ctx, currency, rc.formatSummary(*finished)); err != nil {
+				errs = append(errs, fmt.Errorf("sending %s summary: %w", currency, err))
+			}
+		}
+
+		if err := rc.checkSpread(ctx, rate); err != nil {
+			errs = append(errs, fmt.Errorf("sending %s spread alert: %w", currency, err))
+		}
+
+		var observed *Rate
+		if o, ok := rc.observed[currency]; ok {
+			observed = &o
+		}
+		rc.observed[currency] = rate
+		if err := rc.checkRules(ctx, observed, rate); err != nil {
+			errs = append(errs, fmt.Errorf("sending %s rule alert: %w", currency, err))
+		}
+		if err := rc.checkFrozen(ctx, observed, rate, now); err != nil {
+			errs = append(errs, fmt.Errorf("sending %s frozen rate alert: %w", currency, err))
+		}
+		if err := rc.checkEMACross(ctx, rate); err != nil {
+			errs = append(errs, fmt.Errorf("sending %s EMA alert: %w", currency, err))
+		}
+		if err := rc.checkBigMove(ctx, observed, rate); err != nil {
+			errs = append(errs, fmt.Errorf("sending %s big move alert: %w", currency, err))
+		}
+
+		last, seen := rc.Rate(currency)
+		results = append(results, RateChange{Old: last, New: rate})
+		if seen && ratesEqual(rate, last, rc.tolerance) {
+			// No change in rate
+			continue
+		}
+
+		if seen && !rc.significant(last, rate) {
+			// Moved, but not enough to be worth a message; keep comparing against the stored rate.
+			continue
+		}
+
+		results[len(results)-1].Changed = true
+		changed = true
+
+		// keep the previous rate around so the message can show how much it moved
+		var prev *Rate
+		if seen {
+			prev = &last
+		}
+
+		rc.mu.Lock()
+		rc.rates[currency] = rate
+		rc.lastChange = now
+		rc.mu.Unlock()
+		if rc.store != nil {
+			if err := rc.store.SaveRate(ctx, rate, now); err != nil {
+				errs = append(errs, fmt.Errorf("storing %s rate: %w", currency, err))
+			}
+		}
+		for _, fn := range rc.onChange {
+			fn(last, rate)
+		}
+		rc.publishUpdate(RateEvent{Old: last, New: rate, Time: now})
+		if !rc.changeNotifications || (startup && !seen) {
+			// first rates after startup go out in the startup message below
+			continue
+		}
+		ev := Event{Kind: EventRateChange, Rate: rate, Previous: prev, Time: rate.Updated}
+		if quiet {
+			rc.holdChange(ev)
+			continue
+		}
+		if rc.recentlySent(rate, now) {
+			rc.logger.Info("same rate was sent recently, not sending again", "currency", currency, "buy", rate.Buy, "sell", rate.Sell)
+			continue
+		}
+		if rc.batchChanges {
+			batch = append(batch, ev)
+			continue
+		}
+		if err := rc.notify(ctx, ev); err != nil {
+			errs = append(errs, fmt.Errorf("notifying %s rate: %w", currency, err))
+		}
+	}
+	if changed && rc.statePath != "" {
+		if err := rc.saveState(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(batch) > 0 {
+		at := batch[0].Time
+		ev := Event{Kind: EventRateChanges, Changes: batch, Text: rc.formatBatch(batch, at), Time: at}
+		if err := rc.notify(ctx, ev); err != nil {
+			errs = append(errs, fmt.Errorf("notifying rate changes: %w", err))
+		}
+	}
+	if startup {
+		rc.announced = true
+		if err := rc.alert(ctx, "", rc.formatStartup(rates)); err != nil {
+			errs = append(errs, fmt.Errorf("sending startup message: %w", err))
+		}
+	}
+	if rc.heartbeat > 0 && !quiet {
+		// the first heartbeat is due one heartbeat interval after the first check
+		if rc.lastHeartbeat.IsZero() {
+			rc.lastHeartbeat = now
+		} else if now.Sub(rc.lastHeartbeat) >= rc.heartbeat {
+			rc.lastHeartbeat = now
+			ev := Event{Kind: EventHeartbeat, Text: rc.formatHeartbeat(rates), Time: now}
+			if err := rc.notify(ctx, ev); err != nil {
+				errs = append(errs, fmt.Errorf("sending heartbeat: %w", err))
+			}
+		}
+	}
+	return results, errors.Join(errs...)
+}
+
+// alertTableMissing alerts once that the scraper appears broken, also during the quiet
+// hours. It stays quiet until a fetch succeeds again.
+func (rc *RateChecker) alertTableMissing(ctx context.Context, cause error) error {
+	if !rc.alertOnMissingTable || rc.tableMissingAlerted {
+		return nil
+	}
+	rc.tableMissingAlerted = true
+
+	text := fmt.Sprintf("⚠️ rate scraper is broken: %v", cause)
+	return rc.notify(ctx, Event{Kind: EventAlert, Text: text, Time: rc.clock.Now()})
+}
+
+// checkSpread sends an alert when the spread of rate widens beyond the configured
+// threshold. It alerts once per widening and rearms when the spread narrows again.
+func (rc *RateChecker) checkSpread(ctx context.Context, rate Rate) error {
+	if rc.spreadAlert == 0 {
+		return nil
+	}
+
+	wide := rate.Spread() > rc.spreadAlert
+	alerted := rc.spreadAlerted[rate.Currency]
+	rc.spreadAlerted[rate.Currency] = wide
+	if !wide || alerted {
+		return nil
+	}
+
+	return rc.alert(ctx, rate.Currency, fmt.Sprintf("⚠️ %s spread widened to %s (threshold %s)", rate.Currency, rc.number(rate.Spread()), rc.number(rc.spreadAlert)))
+}
+
+// sentRate is a buy/sell pair and when it was sent.
+type sentRate struct {
+	buy, sell float64
+	at        time.Time
+}
+
+// checkFrozen sends an alert when rate hasn't moved since the previous fetch for longer than
+// the configured window, which usually means the page or a cache in front of it is stuck.
+// It alerts once and rearms when the rate changes again. Time over days Run skips doesn't
+// count.
+func (rc *RateChecker) checkFrozen(ctx context.Context, prev *Rate, rate Rate, now time.Time) error {
+	if rc.frozenAfter == 0 {
+		return nil
 	}
 
-	// If there's no rate or zero, just log it. Zero might indicate a parsing issue.
-	if usdRate.Buy == 0 || usdRate.Sell == 0 {
-		log.Println("Fetched a rate of 0, which is unexpected; skipping message send.")
-		return
+	if prev == nil || !ratesEqual(*prev, rate, rc.tolerance) {
+		rc.lastChanged[rate.Currency] = now
+		rc.frozenAlerted[rate.Currency] = false
+		return nil
 	}
+	if rc.skippedBetween(rc.lastChanged[rate.Currency], now) {
+		// the page isn't checked on skipped days, and rates usually don't move over them
+		// anyway, so counting starts over
+		rc.lastChanged[rate.Currency] = now
+		return nil
+	}
+	unchanged := now.Sub(rc.lastChanged[rate.Currency])
+	if unchanged < rc.frozenAfter || rc.frozenAlerted[rate.Currency] {
+		return nil
+	}
+	rc.frozenAlerted[rate.Currency] = true
+
+	return rc.alert(ctx, rate.Currency, fmt.Sprintf("⚠️ %s rate appears frozen: unchanged at %s / %s for %s", rate.Currency, rc.number(rate.Buy), rc.number(rate.Sell), unchanged.Round(time.Minute)))
+}
+
+// recentlySent reports whether the same buy/sell pair of rate was already sent within
+// the dedup window, and otherwise remembers it as sent now. Pairs are remembered for
+// the whole window, so a rate flickering between two values is only sent twice.
+func (rc *RateChecker) recentlySent(rate Rate, now time.Time) bool {
+	if rc.dedupWindow == 0 {
+		return false
+	}
+
+	recent := rc.sent[rate.Currency][:0]
+	for _, s := range rc.sent[rate.Currency] {
+		if now.Sub(s.at) < rc.dedupWindow {
+			recent = append(recent, s)
+		}
+	}
+	rc.sent[rate.Currency] = recent
+
+	for _, s := range recent {
+		if nearlyEqual(s.buy, rate.Buy, rc.tolerance) && nearlyEqual(s.sell, rate.Sell, rc.tolerance) {
+			return true
+		}
+	}
+	rc.sent[rate.Currency] = append(recent, sentRate{buy: rate.Buy, sell: rate.Sell, at: now})
+	return false
+}
+
+// significant reports whether buy or sell moved from prev to rate by at least
+// one of the configured thresholds. Without thresholds any move is significant.
+func (rc *RateChecker) significant(prev, rate Rate) bool {
+	if rc.minChange == 0 && rc.minPercentChange == 0 {
+		return true
+	}
+	return rc.movedEnough(prev.Buy, rate.Buy) || rc.movedEnough(prev.Sell, rate.Sell)
+}
+
+func (rc *RateChecker) movedEnough(old, new float64) bool {
+	delta := math.Abs(new - old)
+	if rc.minChange > 0 && delta >= rc.minChange {
+		return true
+	}
+	return rc.minPercentChange > 0 && old != 0 && delta/old*100 >= rc.minPercentChange
+}
+
+// LastRates returns up to n most recently stored rates, newest first.
+// It returns nil when no store is configured.
+func (rc *RateChecker) LastRates(ctx context.Context, n int) ([]RateRecord, error) {
+	if rc.store == nil {
+		return nil, nil
+	}
+	return rc.store.LastRates(ctx, n)
+}
+
+// Validate fetches the page once and checks that the configured selectors find the rate
+// table and a non-zero buy and sell value for every watched currency, so a misconfiguration
+// shows at startup instead of as failing checks later. Nothing is stored or sent.
+func (rc *RateChecker) Validate(ctx context.Context) error {
+	rates, err := rc.scrape(ctx, rc.url, rc.sources[0].Name())
+	switch {
+	case errors.Is(err, ErrRateTableNotFound):
+		return fmt.Errorf("row selector %q matches nothing on %s: %w", rc.selectors.Row, rc.url, err)
+	case err != nil:
+		return fmt.Errorf("validating selectors against %s: %w", rc.url, err)
+	}
+	for _, currency := range rc.currencies {
+		if r := rates[currency]; r.Buy <= 0 || r.Sell <= 0 {
+			return fmt.Errorf("value selector %q gives %s buy %v and sell %v on %s, expected positive values",
+				rc.selectors.Value, currency, r.Buy, r.Sell, rc.url)
+		}
+	}
+	return nil
+}
+
+// Rates returns a copy of the last stored rate per currency code. It's safe to call while
+// Run is active.
+func (rc *RateChecker) Rates() map[string]Rate {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+	return maps.Clone(rc.rates)
+}
+
+// Rate returns the last stored rate of currency and whether one was stored yet. It's safe
+// to call while Run is active.
+func (rc *RateChecker) Rate(currency string) (Rate, bool) {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+	r, ok := rc.rates[normalizeCurrency(currency)]
+	return r, ok
+}
 
-	if usdRate.Buy == rc.USDRate.Buy && usdRate.Sell == rc.USDRate.Sell {
-		// No change in rate
-		return
+// FetchCurrentRate retrieves the current exchange rates of the watched currencies, keyed by currency code.
+// It only scrapes the page; no change detection is done and no message is sent. Rates fetched
+// less than the cache TTL ago, by a check or an earlier call, are returned without scraping
+// again; see WithCacheTTL.
+func (rc *RateChecker) FetchCurrentRate(ctx context.Context) (map[string]Rate, error) {
+	if rc.cacheTTL > 0 {
+		rc.cacheMu.Lock()
+		rates, at := rc.cached, rc.cachedAt
+		rc.cacheMu.Unlock()
+		if rates != nil && rc.clock.Now().Sub(at) < rc.cacheTTL {
+			return maps.Clone(rates), nil
+		}
 	}
+	return rc.fetch(ctx)
+}
 
-	rc.USDRate = usdRate
-	if err := rc.sendTelegramMessage(ctx, usdRate); err != nil {
-		log.Printf("Error sending Telegram message: %v\n", err)
+// fetch scrapes the primary source and refreshes the cache.
+func (rc *RateChecker) fetch(ctx context.Context) (map[string]Rate, error) {
+	rates, err := rc.sources[0].Fetch(ctx)
+	// a zero rate tends to go away on the next load, unlike network errors it isn't
+	// covered by the request retries
+	for i := 0; i < rc.zeroRetries && errors.Is(err, ErrZeroRate); i++ {
+		rc.logger.Warn("page showed a zero rate, fetching again", "attempt", i+1, "max_retries", rc.zeroRetries, "error", err)
+		rates, err = rc.sources[0].Fetch(ctx)
+	}
+	if err == nil && rc.cacheTTL > 0 {
+		rc.cacheMu.Lock()
+		rc.cached, rc.cachedAt = maps.Clone(rates), rc.clock.Now()
+		rc.cacheMu.Unlock()
 	}
+	return rates, err
 }
 
-// fetchCurrentRate retrieves the current exchange rate from the given URL.
-func (rc *RateChecker) fetchCurrentRate(ctx context.Context) (USDRate, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+// FetchRates scrapes the current rates of currencies once, without setting up any notifier.
+// It is meant for one-off lookups and scripts that don't need a long-running RateChecker.
+func FetchRates(ctx context.Context, currencies []string, opts ...Option) (map[string]Rate, error) {
+	rc, err := newRateChecker(currencies, opts...)
 	if err != nil {
-		return USDRate{}, fmt.Errorf("creating request: %w", err)
+		return nil, err
 	}
+	return rc.FetchCurrentRate(ctx)
+}
 
-	resp, err := rc.client.Do(req)
+// scrape retrieves the rates of the watched currencies from a rico.ge page.
+func (rc *RateChecker) scrape(ctx context.Context, pageURL, source string) (map[string]Rate, error) {
+	resp, err := rc.getWithRetry(ctx, pageURL)
 	if err != nil {
-		return USDRate{}, fmt.Errorf("fetching URL: %w", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return USDRate{}, fmt.Errorf("received non-200 response code: %d", resp.StatusCode)
+		return nil, fmt.Errorf("received non-200 response code: %d", resp.StatusCode)
 	}
 
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	body, err := readBody(resp.Body, rc.maxBodySize)
 	if err != nil {
-		return USDRate{}, fmt.Errorf("parsing HTML: %w", err)
+		return nil, err
+	}
+	if final := resp.Request.URL.String(); final != pageURL {
+		rc.logger.Warn("page was redirected", "url", pageURL, "final_url", final)
 	}
 
-	var ret USDRate
-	doc.Find("tbody.first-table-body tr").Each(func(i int, s *goquery.Selection) {
-		// only parse USD
-		if i != 0 {
-			return
-		}
+	doc, err := parseDocument(body, resp.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, err
+	}
 
-		currency := s.Find("td.flag-title").Text()
+	updated, ok := rc.parseUpdated(doc)
+	if !ok {
+		updated = rc.clock.Now()
+	}
 
-		// The currency values are likely in the subsequent cells:
-		// 0th "currency-value" td might be Buy,
-		// 1st "currency-value" td might be Sell (or vice versa).
-		buyStr := s.Find("td.currency-value").Eq(0).Text()
-		sellStr := s.Find("td.currency-value").Eq(1).Text()
+	ret := make(map[string]Rate, len(rc.currencies))
+	for _, currency := range rc.currencies {
+		rate, err := parseRate(doc, currency, rc.selectors)
+		if err != nil {
+			rc.dumpBody(pageURL, body, err)
+			return nil, fmt.Errorf("%w on %s", err, pageURL)
+		}
+		// round right away so float noise can't show up as a change
+		rate = rate.rounded(rc.precision)
+		rate.Source, rate.Updated = source, updated
 
-		// Replace the comma with a dot for proper float parsing
-		buyStr = strings.ReplaceAll(buyStr, ",", ".")
-		sellStr = strings.ReplaceAll(sellStr, ",", ".")
+		if rate.hasTransfer() {
+			rc.logger.Info("parsed rate", "currency", currency, "buy", rate.Buy, "sell", rate.Sell, "transfer_buy", rate.TransferBuy, "transfer_sell", rate.TransferSell)
+		} else {
+			rc.logger.Info("parsed rate", "currency", currency, "buy", rate.Buy, "sell", rate.Sell)
+		}
+		ret[currency] = rate
+	}
+	return ret, nil
+}
 
-		ret.Buy, err = strconv.ParseFloat(buyStr, 64)
+// ParseRateFromHTML parses the rate of currency out of a rico.ge page read from r, the same
+// way a live fetch does, rounded to the default precision. It's meant for checking saved
+// copies of the page, so the charset is taken from a byte order mark or the page's meta
+// tags; Source and Updated are left empty.
+func ParseRateFromHTML(r io.Reader, currency string) (Rate, error) {
+	body, err := readBody(r, defaultMaxBodySize)
+	if err != nil {
+		return Rate{}, err
+	}
+	doc, err := parseDocument(body, "")
+	if err != nil {
+		return Rate{}, err
+	}
+	rate, err := parseRate(doc, normalizeCurrency(currency), defaultSelectors)
+	if err != nil {
+		return Rate{}, err
+	}
+	return rate.rounded(defaultPrecision), nil
+}
+
+// parseDocument decodes a page to UTF-8 and parses it. The charset is taken from a byte
+// order mark, then from contentType, the Content-Type header the page was served with,
+// then from the page's meta tags. A page without any of them is read as UTF-8 if it's
+// valid UTF-8.
+func parseDocument(body []byte, contentType string) (*goquery.Document, error) {
+	// the currency names are Georgian, so a page in another charset must be decoded first
+	var r io.Reader = bytes.NewReader(body)
+	if enc, name, certain := charset.DetermineEncoding(body, contentType); certain || !utf8.Valid(body) {
+		decoded, err := enc.NewDecoder().Bytes(body)
 		if err != nil {
-			log.Printf("Error converting buyVal: %v", err)
+			return nil, fmt.Errorf("decoding page charset %s: %w", name, err)
 		}
+		r = bytes.NewReader(decoded)
+	}
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("parsing HTML: %w", err)
+	}
+	return doc, nil
+}
 
-		ret.Sell, err = strconv.ParseFloat(sellStr, 64)
-		if err != nil {
-			log.Printf("Error converting sellVal: %v", err)
+// Selectors are the CSS selectors locating the rate table on the page.
+type Selectors struct {
+	Row      string // one row per currency, default "tbody.first-table-body tr"
+	Currency string // cell of a row holding the currency code, default "td.flag-title"
+	Value    string // cells of a row holding the buy and then the sell value, default "td.currency-value"
+	// rows of the optional transfer (non-cash) rate table, laid out like the cash one,
+	// default "tbody.second-table-body tr"
+	TransferRow string
+}
+
+var defaultSelectors = Selectors{
+	Row:      "tbody.first-table-body tr",
+	Currency: "td.flag-title",
+	Value:    "td.currency-value",
+
+	TransferRow: "tbody.second-table-body tr",
+}
+
+// withDefaults fills the empty selectors of s with the defaults.
+func (s Selectors) withDefaults() Selectors {
+	if s.Row == "" {
+		s.Row = defaultSelectors.Row
+	}
+	if s.Currency == "" {
+		s.Currency = defaultSelectors.Currency
+	}
+	if s.Value == "" {
+		s.Value = defaultSelectors.Value
+	}
+	if s.TransferRow == "" {
+		s.TransferRow = defaultSelectors.TransferRow
+	}
+	return s
+}
+
+// parseRate extracts the buy and sell values of currency from a parsed rico.ge page,
+// finding them with sel.
+// It only looks at the document, so it can be fed saved copies of the page.
+func parseRate(doc *goquery.Document, currency string, sel Selectors) (Rate, error) {
+	rows := doc.Find(sel.Row)
+	if rows.Length() == 0 {
+		return Rate{}, ErrRateTableNotFound
+	}
+	row := findRow(rows, currency, sel)
+	if row.Length() == 0 {
+		return Rate{}, fmt.Errorf("currency %q not found", currency)
+	}
+
+	rate := Rate{Currency: currency}
+	var err error
+	if rate.Buy, rate.Sell, err = parseValues(row, currency, sel); err != nil {
+		return Rate{}, err
+	}
+
+	// pages without a transfer table, or without the currency in it, only have cash rates
+	if row := findRow(doc.Find(sel.TransferRow), currency, sel); row.Length() > 0 {
+		if rate.TransferBuy, rate.TransferSell, err = parseValues(row, currency, sel); err != nil {
+			return Rate{}, fmt.Errorf("transfer rate: %w", err)
 		}
+	}
+	return rate, nil
+}
 
-		// Now buyVal and sellVal are floats you can work with.
-		fmt.Printf("Currency: %s, ყიდვა: %.4f, გაყიდვა: %.4f\n", currency, ret.Buy, ret.Sell)
-	})
+// findRow returns the row of rows showing currency, empty when there's none.
+func findRow(rows *goquery.Selection, currency string, sel Selectors) *goquery.Selection {
+	// match rows by currency code, the order of rows on the page isn't stable
+	return rows.FilterFunction(func(_ int, s *goquery.Selection) bool {
+		return normalizeCurrency(s.Find(sel.Currency).Text()) == currency
+	}).First()
+}
 
-	return ret, nil
+// parseValues parses the buy and sell values of a table row.
+func parseValues(row *goquery.Selection, currency string, sel Selectors) (buy, sell float64, err error) {
+	// The currency values are likely in the subsequent cells:
+	// 0th value cell might be Buy,
+	// 1st value cell might be Sell (or vice versa).
+	buyStr := row.Find(sel.Value).Eq(0).Text()
+	sellStr := row.Find(sel.Value).Eq(1).Text()
+
+	if buy, err = parseValue(buyStr); err != nil {
+		return 0, 0, fmt.Errorf("parsing %s buy value: %w", currency, err)
+	}
+	if sell, err = parseValue(sellStr); err != nil {
+		return 0, 0, fmt.Errorf("parsing %s sell value: %w", currency, err)
+	}
+	return buy, sell, nil
 }
 
-// sendTelegramMessage sends the current exchange rate message to the specified Telegram channel.
-func (rc *RateChecker) sendTelegramMessage(ctx context.Context, rate USDRate) error {
-	currentDate := time.Now().In(rc.location)
-	formattedTime := currentDate.Format(timeFormat)
-	messageText := fmt.Sprintf(`%s - 1$ USD 
-	ყიდვა: %.4f, გაყიდვა: %.4f`, formattedTime, rate.Buy, rate.Sell)
+// parseUpdated finds the time the page says its rates were last updated.
+func (rc *RateChecker) parseUpdated(doc *goquery.Document) (time.Time, bool) {
+	m := updatedPattern.FindStringSubmatch(doc.Find(rc.updatedSelector).First().Text())
+	if m == nil {
+		return time.Time{}, false
+	}
 
-	telegramURL := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", rc.botToken)
+	var n [6]int
+	for i, v := range m[1:] {
+		if v != "" {
+			n[i], _ = strconv.Atoi(v)
+		}
+	}
+	day, month, year, hour, minute, sec := n[0], n[1], n[2], n[3], n[4], n[5]
+	return time.Date(year, time.Month(month), day, hour, minute, sec, 0, rc.pageLocation), true
+}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, telegramURL, nil)
+// parseValue parses a rate cell. A valid rate is never zero, so zero is reported as an
+// error too; it usually means the page layout changed.
+func parseValue(str string) (float64, error) {
+	num, err := normalizeNumber(str)
 	if err != nil {
-		return fmt.Errorf("creating telegram request: %w", err)
+		return 0, err
 	}
 
-	q := req.URL.Query()
-	q.Add("chat_id", rc.channelID)
-	q.Add("text", messageText)
-	req.URL.RawQuery = q.Encode()
+	v, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, err
+	}
+	if v == 0 {
+		return 0, fmt.Errorf("%w: got %q", ErrZeroRate, str)
+	}
+	return v, nil
+}
 
-	resp, err := rc.client.Do(req)
+// normalizeNumber turns a number as shown on a page, like "2,70", "₾ 2.70" or "1.234,56",
+// into the plain "1234.56" form ParseFloat accepts. Anything but digits and separators is
+// dropped. When both "," and "." appear the last one is the decimal separator; a
+// separator that appears more than once only groups thousands. A number ending in a
+// separator, with two separators in a row, with a decimal separator that appears more
+// than once or with groups that aren't three digits is malformed.
+func normalizeNumber(str string) (string, error) {
+	var b strings.Builder
+	for _, r := range str {
+		if (r >= '0' && r <= '9') || r == ',' || r == '.' {
+			b.WriteRune(r)
+		}
+	}
+	num := b.String()
+	if strings.IndexFunc(num, isDigit) < 0 {
+		return "", fmt.Errorf("no number in %q", str)
+	}
+	if strings.HasSuffix(num, ",") || strings.HasSuffix(num, ".") {
+		return "", fmt.Errorf("%q ends in a separator", str)
+	}
+	if strings.Contains(num, ",,") || strings.Contains(num, "..") || strings.Contains(num, ",.") || strings.Contains(num, ".,") {
+		return "", fmt.Errorf("%q has separators in a row", str)
+	}
+
+	decimal := ""
+	switch commas, dots := strings.Count(num, ","), strings.Count(num, "."); {
+	case commas > 0 && dots > 0:
+		decimal = num[max(strings.LastIndex(num, ","), strings.LastIndex(num, ".")):][:1]
+		if strings.Count(num, decimal) > 1 {
+			return "", fmt.Errorf("%q has no unambiguous decimal separator", str)
+		}
+	case commas == 1:
+		decimal = ","
+	case dots == 1:
+		decimal = "."
+	}
+
+	// everything before the decimal separator is grouping
+	whole, frac := num, ""
+	if decimal != "" {
+		i := strings.LastIndex(num, decimal)
+		whole, frac = num[:i], "."+num[i+1:]
+	}
+	groups := strings.FieldsFunc(whole, func(r rune) bool { return r == ',' || r == '.' })
+	for i, g := range groups {
+		if len(groups) > 1 && (i == 0 && len(g) > 3 || i > 0 && len(g) != 3) {
+			return "", fmt.Errorf("%q has a misplaced separator", str)
+		}
+	}
+	return strings.Join(groups, "") + frac, nil
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+// branchURL returns rawURL with the query parameter selecting branch set.
+func branchURL(rawURL, branch string) (string, error) {
+	u, err := url.Parse(rawURL)
 	if err != nil {
-		return fmt.Errorf("sending telegram message: %w", err)
+		return "", fmt.Errorf("invalid URL %q: %w", rawURL, err)
 	}
-	defer resp.Body.Close()
+	q := u.Query()
+	q.Set(branchParam, branch)
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("received non-200 status from telegram: %d", resp.StatusCode)
+// watches reports whether the given currency code is one of the watched currencies.
+func (rc *RateChecker) watches(currency string) bool {
+	for _, c := range rc.currencies {
+		if c == currency {
+			return true
+		}
 	}
+	return false
+}
 
-	log.Printf("Message sent: %s\n", messageText)
-	return nil
+// normalizeCurrency trims and upper-cases a currency code so it can be compared.
+func normalizeCurrency(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// supportedCurrencies are the codes rico.ge quotes against the lari.
+var supportedCurrencies = map[string]bool{
+	"AED": true, "AMD": true, "AUD": true, "AZN": true, "BYN": true, "CAD": true,
+	"CHF": true, "CNY": true, "CZK": true, "DKK": true, "EUR": true, "GBP": true,
+	"ILS": true, "JPY": true, "KZT": true, "NOK": true, "PLN": true, "RUB": true,
+	"SEK": true, "TRY": true, "UAH": true, "USD": true,
+}
+
+// knownCurrency reports whether rico.ge quotes the normalized code against the lari.
+func knownCurrency(code string) bool {
+	return supportedCurrencies[code]
 }
@@ -0,0 +1,520 @@
+package rico
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNormalizeNumber(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "2.70", want: "2.70"},
+		{in: "2,70", want: "2.70"},
+		{in: " ₾ 2.7000 ", want: "2.7000"},
+		{in: "1.234,56", want: "1234.56"},
+		{in: "1,234.56", want: "1234.56"},
+		{in: "1.234.567", want: "1234567"},
+		{in: "1,234,567.89", want: "1234567.89"},
+		{in: "270", want: "270"},
+		{in: ",70", want: ".70"},
+
+		{in: "", wantErr: true},
+		{in: "n/a", wantErr: true},
+		{in: ",", wantErr: true},
+		{in: "2,70,", wantErr: true},
+		{in: "2.70.", wantErr: true},
+		{in: "2,", wantErr: true},
+		{in: "2,,70", wantErr: true},
+		{in: "2.,70", wantErr: true},
+		{in: "2.70.5", wantErr: true},
+		{in: "1,234.5,6", wantErr: true},
+		{in: "1.23,4.56", wantErr: true},
+		{in: "12345.678.901", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := normalizeNumber(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("normalizeNumber(%q) = %q, want an error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("normalizeNumber(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseValue(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    float64
+		wantErr error // error the failure must wrap, nil accepts any
+		fail    bool
+	}{
+		{in: "2.7000", want: 2.7},
+		{in: "2,7200", want: 2.72},
+		{in: "1.234,5", want: 1234.5},
+		{in: "0", fail: true, wantErr: ErrZeroRate},
+		{in: "0,00", fail: true, wantErr: ErrZeroRate},
+		{in: "2,70,", fail: true},
+		{in: "2.70.", fail: true},
+		{in: "-", fail: true},
+	}
+	for _, tt := range tests {
+		got, err := parseValue(tt.in)
+		if tt.fail {
+			if err == nil {
+				t.Errorf("parseValue(%q) = %v, want an error", tt.in, got)
+			} else if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("parseValue(%q) error = %v, want %v", tt.in, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("parseValue(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+// recorder is a Notifier keeping the events it's told about.
+type recorder struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (r *recorder) Notify(_ context.Context, ev Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, ev)
+	return nil
+}
+
+// take returns the events recorded since the last call.
+func (r *recorder) take() []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ev := r.events
+	r.events = nil
+	return ev
+}
+
+// count returns how many events are recorded.
+func (r *recorder) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.events)
+}
+
+// newTestChecker creates a checker without Telegram that notifies rec and doesn't log.
+func newTestChecker(t *testing.T, rec *recorder, opts ...Option) *RateChecker {
+	t.Helper()
+	opts = append([]Option{WithNotifier(rec), WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))}, opts...)
+	rc, err := newRateChecker(nil, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rc
+}
+
+func TestRatesEqual(t *testing.T) {
+	base := Rate{Currency: "USD", Buy: 2.7, Sell: 2.72}
+	transfer := Rate{Currency: "USD", Buy: 2.7, Sell: 2.72, TransferBuy: 2.69, TransferSell: 2.73}
+	tests := []struct {
+		name string
+		a, b Rate
+		eps  float64
+		want bool
+	}{
+		{"identical", base, base, defaultTolerance, true},
+		{"float noise", base, Rate{Buy: 0.1 + 0.2 + 2.4, Sell: 2.72}, defaultTolerance, true},
+		{"within tolerance", base, Rate{Buy: 2.7000005, Sell: 2.7199995}, defaultTolerance, true},
+		{"at the tolerance", Rate{Buy: 2.5, Sell: 3}, Rate{Buy: 2.5 + 0.25, Sell: 3}, 0.25, true},
+		{"zero tolerance", base, Rate{Buy: 2.7000001, Sell: 2.72}, 0, false},
+		{"buy moved", base, Rate{Buy: 2.7001, Sell: 2.72}, defaultTolerance, false},
+		{"sell moved", base, Rate{Buy: 2.7, Sell: 2.75}, defaultTolerance, false},
+		{"beyond a loose tolerance", base, Rate{Buy: 2.72, Sell: 2.72}, 0.01, false},
+		{"transfer moved", transfer, Rate{Buy: 2.7, Sell: 2.72, TransferBuy: 2.68, TransferSell: 2.73}, defaultTolerance, false},
+		{"transfer appeared", base, transfer, defaultTolerance, false},
+		{"other fields ignored", base, Rate{Currency: "USD", Buy: 2.7, Sell: 2.72, Source: "other"}, defaultTolerance, true},
+	}
+	for _, tt := range tests {
+		if got := ratesEqual(tt.a, tt.b, tt.eps); got != tt.want {
+			t.Errorf("%s: ratesEqual(%v, %v, %g) = %v, want %v", tt.name, tt.a, tt.b, tt.eps, got, tt.want)
+		}
+		if got := ratesEqual(tt.b, tt.a, tt.eps); got != tt.want {
+			t.Errorf("%s: ratesEqual isn't symmetric", tt.name)
+		}
+	}
+}
+
+// The fixture is windows-1251 with a no-break space after every currency code, which only
+// trims away once the page is decoded.
+const windows1251Fixture = "testdata/rates-windows-1251.html"
+
+func TestScrapeDecodesCharset(t *testing.T) {
+	page, err := os.ReadFile(windows1251Fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=windows-1251")
+		w.Write(page)
+	}))
+	defer srv.Close()
+
+	rates, err := FetchRates(context.Background(), []string{"USD", "EUR"}, WithURL(srv.URL),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := rates["USD"]; r.Buy != 2.7 || r.Sell != 2.72 {
+		t.Errorf("USD = %v, want buy 2.7 and sell 2.72", r)
+	}
+	if r := rates["EUR"]; r.Buy != 2.95 || r.Sell != 3.01 {
+		t.Errorf("EUR = %v, want buy 2.95 and sell 3.01", r)
+	}
+}
+
+func TestParseRateFromHTMLDecodesCharset(t *testing.T) {
+	f, err := os.Open(windows1251Fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	// the charset comes from the page's meta tag here
+	rate, err := ParseRateFromHTML(f, "USD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rate.Buy != 2.7 || rate.Sell != 2.72 {
+		t.Errorf("ParseRateFromHTML = %v, want buy 2.7 and sell 2.72", rate)
+	}
+}
+
+// panickingSource is a Source whose every Fetch panics.
+type panickingSource struct {
+	fetches atomic.Int32
+}
+
+func (s *panickingSource) Name() string { return "panicking" }
+
+func (s *panickingSource) Fetch(context.Context) (map[string]Rate, error) {
+	s.fetches.Add(1)
+	panic("source bug")
+}
+
+// panickingNotifier is a Notifier panicking on the first event and recording the rest.
+type panickingNotifier struct {
+	recorder
+	panicked atomic.Bool
+}
+
+func (n *panickingNotifier) Notify(ctx context.Context, ev Event) error {
+	if n.panicked.CompareAndSwap(false, true) {
+		panic("notifier bug")
+	}
+	return n.recorder.Notify(ctx, ev)
+}
+
+// ratesPage serves a rate table whose USD buy value goes up by 0.01 on every request.
+func ratesPage(t *testing.T) *httptest.Server {
+	t.Helper()
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buy := 2.7 + float64(requests.Add(1))/100
+		fmt.Fprintf(w, `<table><tbody class="first-table-body"><tr><td class="flag-title">USD</td>`+
+			`<td class="currency-value">%.4f</td><td class="currency-value">%.4f</td></tr></tbody></table>`, buy, buy+0.02)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestRunSurvivesPanics(t *testing.T) {
+	src := &panickingSource{}
+	n := &panickingNotifier{}
+	rc, err := newRateChecker(nil, WithURL(ratesPage(t).URL), WithInterval(10*time.Millisecond),
+		WithSource(src), WithNotifier(n), WithCacheTTL(-1), WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		rc.Run(ctx)
+	}()
+	// the first check panics in the notifier, every one panics in the source
+	deadline := time.After(5 * time.Second)
+	for src.fetches.Load() < 3 || n.count() == 0 {
+		select {
+		case <-deadline:
+			cancel()
+			t.Fatalf("checks stopped after a panic: %d fetches, %d notifications", src.fetches.Load(), n.count())
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+	cancel()
+	<-done
+
+	if !n.panicked.Load() {
+		t.Error("the notifier never panicked")
+	}
+}
+
+func TestConcurrentReadsDuringRun(t *testing.T) {
+	rc, err := newRateChecker(nil, WithURL(ratesPage(t).URL), WithInterval(5*time.Millisecond),
+		WithCycleTimeout(time.Second), WithCacheTTL(-1), WithNotifier(&recorder{}),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		rc.Run(ctx)
+	}()
+
+	health, rate := rc.HealthHandler(), rc.RateHandler()
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// keep reading until several checks ran alongside
+			for i := 0; i < 200 || len(rc.RecentRates()) < 3; i++ {
+				rc.Rates()
+				rc.Rate("USD")
+				rc.Status()
+				rc.RecentRates()
+				health.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+				rate.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/rate", nil))
+			}
+		}()
+	}
+	wg.Wait()
+	cancel()
+	<-done
+
+	if r, ok := rc.Rate("USD"); !ok || r.Buy <= 2.7 {
+		t.Errorf("Rate(USD) = %v, %v after Run, want a fetched rate", r, ok)
+	}
+	if st := rc.Status(); st.LastFetch.IsZero() {
+		t.Error("Status().LastFetch is zero after Run")
+	}
+}
+
+// testClock is a Clock showing whatever it's set to.
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+}
+
+func TestFrozenAlertIgnoresSkippedDays(t *testing.T) {
+	var buy atomic.Value
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<table><tbody class="first-table-body"><tr><td class="flag-title">USD</td>`+
+			`<td class="currency-value">%s</td><td class="currency-value">2.7200</td></tr></tbody></table>`, buy.Load())
+	}))
+	defer srv.Close()
+
+	tbilisi, err := time.LoadLocation(timezone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := &testClock{}
+	rec := &recorder{}
+	rc := newTestChecker(t, rec, WithURL(srv.URL), WithClock(clock), WithCacheTTL(-1), WithFrozenAlert(2*time.Hour),
+		WithSkipWeekdays(time.Saturday, time.Sunday), WithHolidays("2026-10-21"))
+
+	checks := []struct {
+		name   string
+		at     time.Time
+		buy    string
+		frozen bool
+	}{
+		{"friday", time.Date(2026, 10, 16, 16, 0, 0, 0, tbilisi), "2.7000", false},
+		{"friday later", time.Date(2026, 10, 16, 17, 0, 0, 0, tbilisi), "2.7000", false},
+		{"monday after the weekend", time.Date(2026, 10, 19, 10, 0, 0, 0, tbilisi), "2.7000", false},
+		{"monday an hour later", time.Date(2026, 10, 19, 11, 0, 0, 0, tbilisi), "2.7000", false},
+		{"monday past the window", time.Date(2026, 10, 19, 12, 30, 0, 0, tbilisi), "2.7000", true},
+		{"tuesday, changed", time.Date(2026, 10, 20, 18, 0, 0, 0, tbilisi), "2.7100", false},
+		{"thursday after a holiday", time.Date(2026, 10, 22, 10, 0, 0, 0, tbilisi), "2.7100", false},
+	}
+	for _, c := range checks {
+		clock.set(c.at)
+		buy.Store(c.buy)
+		if _, err := rc.CheckForRateChange(context.Background()); err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		var frozen bool
+		for _, ev := range rec.take() {
+			frozen = frozen || (ev.Kind == EventAlert && strings.Contains(ev.Text, "frozen"))
+		}
+		if frozen != c.frozen {
+			t.Errorf("%s: frozen alert sent = %v, want %v", c.name, frozen, c.frozen)
+		}
+	}
+}
+
+func TestQuietHoursHoldAlerts(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<table><tbody class="first-table-body"><tr><td class="flag-title">USD</td>`+
+			`<td class="currency-value">2.7000</td><td class="currency-value">2.7500</td></tr></tbody></table>`)
+	}))
+	defer srv.Close()
+
+	tbilisi, err := time.LoadLocation(timezone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := &testClock{}
+	rec := &recorder{}
+	rc := newTestChecker(t, rec, WithURL(srv.URL), WithClock(clock), WithCacheTTL(-1), WithSpreadAlert(0.01),
+		WithStartupMessage(true), WithQuietHours(QuietHours{Start: "22:00", End: "07:00"}))
+
+	clock.set(time.Date(2026, 10, 16, 3, 0, 0, 0, tbilisi))
+	if _, err := rc.CheckForRateChange(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if events := rec.take(); len(events) != 0 {
+		t.Fatalf("sent %d messages during the quiet hours, want none: %+v", len(events), events)
+	}
+
+	clock.set(time.Date(2026, 10, 16, 7, 30, 0, 0, tbilisi))
+	if _, err := rc.CheckForRateChange(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	events := rec.take()
+	if len(events) != 2 {
+		t.Fatalf("sent %d messages after the quiet hours, want the spread alert and the startup message: %+v", len(events), events)
+	}
+	if !strings.Contains(events[0].Text, "spread widened") {
+		t.Errorf("first message = %q, want the spread alert", events[0].Text)
+	}
+	for _, ev := range events {
+		if ev.Kind != EventAlert || !ev.Time.Equal(time.Date(2026, 10, 16, 3, 0, 0, 0, tbilisi)) {
+			t.Errorf("got %v message from %v, want an alert raised at 03:00", ev.Kind, ev.Time)
+		}
+	}
+}
+
+// funcSource is a Source fetching with a function.
+type funcSource struct {
+	fetch func(ctx context.Context) (map[string]Rate, error)
+}
+
+func (s funcSource) Name() string { return "other" }
+
+func (s funcSource) Fetch(ctx context.Context) (map[string]Rate, error) { return s.fetch(ctx) }
+
+func TestCheckFetchesSourcesConcurrently(t *testing.T) {
+	otherStarted := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// fetched one after the other, the other source never starts while this waits
+		select {
+		case <-otherStarted:
+		case <-time.After(time.Second):
+			http.Error(w, "other source wasn't fetched meanwhile", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `<table><tbody class="first-table-body"><tr><td class="flag-title">USD</td>`+
+			`<td class="currency-value">2.7000</td><td class="currency-value">2.7200</td></tr></tbody></table>`)
+	}))
+	defer srv.Close()
+
+	other := funcSource{fetch: func(context.Context) (map[string]Rate, error) {
+		close(otherStarted)
+		return map[string]Rate{"USD": {Currency: "USD", Buy: 2.71, Sell: 2.73}}, nil
+	}}
+	rc := newTestChecker(t, &recorder{}, WithURL(srv.URL), WithSource(other), WithCacheTTL(-1), WithRetry(1, 0))
+	if _, err := rc.CheckForRateChange(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckFailsOnPrimarySource(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	other := funcSource{fetch: func(ctx context.Context) (map[string]Rate, error) {
+		// canceled once the primary source failed
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}}
+	rec := &recorder{}
+	rc := newTestChecker(t, rec, WithURL(srv.URL), WithSource(other), WithCacheTTL(-1), WithRetry(1, 0))
+	results, err := rc.CheckForRateChange(context.Background())
+	if err == nil || results != nil {
+		t.Fatalf("CheckForRateChange = %v, %v, want no outcomes and an error", results, err)
+	}
+	if !strings.Contains(err.Error(), "fetching current rate") || errors.Is(err, context.Canceled) {
+		t.Errorf("error = %v, want the primary source's", err)
+	}
+	if _, seen := rc.Rate("USD"); seen || rec.count() > 0 {
+		t.Error("a failed primary fetch stored or sent rates")
+	}
+}
+
+func TestRateJSONPrecision(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<table><tbody class="first-table-body"><tr><td class="flag-title">USD</td>`+
+			`<td class="currency-value">2.712345</td><td class="currency-value">2.7654321</td></tr></tbody></table>`)
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		opts []Option
+		want string
+	}{
+		{nil, `"buy":2.7123,"sell":2.7654`},
+		{[]Option{WithPrecision(6)}, `"buy":2.712345,"sell":2.765432`},
+		{[]Option{WithPrecision(2)}, `"buy":2.71,"sell":2.77`},
+	}
+	for _, tt := range tests {
+		rates, err := FetchRates(context.Background(), nil, append([]Option{WithURL(srv.URL), WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))}, tt.opts...)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := json.Marshal(rates["USD"])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(b), tt.want) {
+			t.Errorf("JSON = %s, want it to contain %s", b, tt.want)
+		}
+	}
+}
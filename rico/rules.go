@@ -0,0 +1,84 @@
+package rico
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Side selects which value of a rate a rule watches.
+type Side int
+
+const (
+	SideBuy Side = iota
+	SideSell
+)
+
+func (s Side) String() string {
+	if s == SideSell {
+		return "sell"
+	}
+	return "buy"
+}
+
+func (s Side) value(r Rate) float64 {
+	if s == SideSell {
+		return r.Sell
+	}
+	return r.Buy
+}
+
+// Direction selects which way a value must cross a rule's level.
+type Direction int
+
+const (
+	CrossAbove Direction = iota
+	CrossBelow
+)
+
+func (d Direction) String() string {
+	if d == CrossBelow {
+		return "below"
+	}
+	return "above"
+}
+
+// AlertRule fires an alert when a value crosses a level, e.g. USD sell crossing above 2.75.
+// It fires once on the crossing itself, not on every check while the value stays past the level.
+type AlertRule struct {
+	Currency  string
+	Side      Side
+	Direction Direction
+	Level     float64
+}
+
+// crossed reports whether the watched value crossed the level between prev and cur.
+func (r AlertRule) crossed(prev, cur Rate) bool {
+	p, c := r.Side.value(prev), r.Side.value(cur)
+	if r.Direction == CrossBelow {
+		return p >= r.Level && c < r.Level
+	}
+	return p <= r.Level && c > r.Level
+}
+
+// checkRules alerts about every rule whose level was crossed between the previous and
+// the current observation of rate's currency.
+func (rc *RateChecker) checkRules(ctx context.Context, prev *Rate, rate Rate) error {
+	if prev == nil {
+		// nothing to cross from on the first observation
+		return nil
+	}
+
+	var errs []error
+	for _, rule := range rc.rules {
+		if rule.Currency != rate.Currency || !rule.crossed(*prev, rate) {
+			continue
+		}
+		text := fmt.Sprintf("🔔 %s %s crossed %s %s: %s",
+			rate.Currency, rule.Side, rule.Direction, rc.number(rule.Level), rc.number(rule.Side.value(rate)))
+		if err := rc.alert(ctx, rate.Currency, text); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,96 @@
+package rico
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestAlertRuleCrossed(t *testing.T) {
+	above := AlertRule{Currency: "USD", Side: SideSell, Direction: CrossAbove, Level: 2.75}
+	below := AlertRule{Currency: "USD", Side: SideBuy, Direction: CrossBelow, Level: 2.65}
+	tests := []struct {
+		name       string
+		rule       AlertRule
+		prev, cur  Rate
+		wantAlerts bool
+	}{
+		{"crossing up", above, Rate{Sell: 2.74}, Rate{Sell: 2.76}, true},
+		{"crossing up from the level", above, Rate{Sell: 2.75}, Rate{Sell: 2.76}, true},
+		{"landing on the level", above, Rate{Sell: 2.74}, Rate{Sell: 2.75}, false},
+		{"staying above", above, Rate{Sell: 2.76}, Rate{Sell: 2.80}, false},
+		{"crossing down past an above rule", above, Rate{Sell: 2.76}, Rate{Sell: 2.74}, false},
+		{"other side moving", above, Rate{Buy: 2.70, Sell: 2.70}, Rate{Buy: 2.80, Sell: 2.70}, false},
+
+		{"crossing down", below, Rate{Buy: 2.66}, Rate{Buy: 2.64}, true},
+		{"crossing down from the level", below, Rate{Buy: 2.65}, Rate{Buy: 2.64}, true},
+		{"landing on the level from above", below, Rate{Buy: 2.66}, Rate{Buy: 2.65}, false},
+		{"staying below", below, Rate{Buy: 2.64}, Rate{Buy: 2.60}, false},
+		{"crossing up past a below rule", below, Rate{Buy: 2.64}, Rate{Buy: 2.66}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.rule.crossed(tt.prev, tt.cur); got != tt.wantAlerts {
+			t.Errorf("%s: crossed(%v, %v) = %v, want %v", tt.name, tt.prev, tt.cur, got, tt.wantAlerts)
+		}
+	}
+}
+
+func TestCheckRules(t *testing.T) {
+	rec := &recorder{}
+	rc := newTestChecker(t, rec, WithAlertRules(
+		AlertRule{Currency: "USD", Side: SideSell, Direction: CrossAbove, Level: 2.75},
+		AlertRule{Currency: "USD", Side: SideSell, Direction: CrossBelow, Level: 2.70},
+	))
+
+	// each step is the sell value of one check
+	steps := []struct {
+		name  string
+		sell  float64
+		alert string // text the alert must contain, empty for none
+	}{
+		{"first observation", 2.80, ""},
+		{"staying above", 2.78, ""},
+		{"crossing down", 2.69, "crossed below"},
+		{"staying below", 2.60, ""},
+		{"landing on the lower level", 2.70, ""},
+		{"landing on the upper level", 2.75, ""},
+		{"crossing up from the level", 2.76, "crossed above"},
+		{"staying above again", 2.90, ""},
+	}
+	var prev *Rate
+	for _, step := range steps {
+		rate := Rate{Currency: "USD", Buy: step.sell - 0.02, Sell: step.sell}
+		if err := rc.checkRules(context.Background(), prev, rate); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		prev = &rate
+
+		events := rec.take()
+		switch {
+		case step.alert == "" && len(events) > 0:
+			t.Errorf("%s: got alert %q, want none", step.name, events[0].Text)
+		case step.alert != "" && len(events) != 1:
+			t.Errorf("%s: got %d alerts, want 1", step.name, len(events))
+		case step.alert != "" && !strings.Contains(events[0].Text, step.alert):
+			t.Errorf("%s: alert %q doesn't mention %q", step.name, events[0].Text, step.alert)
+		}
+	}
+}
+
+func TestCheckRulesNumberFormat(t *testing.T) {
+	rec := &recorder{}
+	rc := newTestChecker(t, rec, WithNumberFormat(2, ","),
+		WithAlertRules(AlertRule{Currency: "USD", Side: SideSell, Direction: CrossAbove, Level: 2.75}))
+
+	prev := Rate{Currency: "USD", Buy: 2.7, Sell: 2.74}
+	if err := rc.checkRules(context.Background(), &prev, Rate{Currency: "USD", Buy: 2.74, Sell: 2.7612}); err != nil {
+		t.Fatal(err)
+	}
+	events := rec.take()
+	if len(events) != 1 {
+		t.Fatalf("got %d alerts, want 1", len(events))
+	}
+	if want := "crossed above 2,75: 2,76"; !strings.Contains(events[0].Text, want) {
+		t.Errorf("alert %q doesn't contain %q", events[0].Text, want)
+	}
+}
@@ -0,0 +1,60 @@
+package rico
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// dateFormat is the layout of holiday dates.
+const dateFormat = "2006-01-02"
+
+// skipDay reports whether Run skips checks on the day of now in the checker's timezone,
+// being a skipped weekday or a holiday, along with that day as a date.
+func (rc *RateChecker) skipDay(now time.Time) (day string, skip bool) {
+	t := now.In(rc.location)
+	day = t.Format(dateFormat)
+	return day, rc.skipWeekdays[t.Weekday()] || rc.holidays[day]
+}
+
+// skippedBetween reports whether any day after the day of from, up to and including the
+// day of to, is one Run skips.
+func (rc *RateChecker) skippedBetween(from, to time.Time) bool {
+	if len(rc.skipWeekdays) == 0 && len(rc.holidays) == 0 {
+		return false
+	}
+	from, last := from.In(rc.location), to.In(rc.location).Format(dateFormat)
+	// noon keeps daylight saving changes from skipping or repeating a day
+	for d := time.Date(from.Year(), from.Month(), from.Day()+1, 12, 0, 0, 0, rc.location); d.Format(dateFormat) <= last; d = d.AddDate(0, 0, 1) {
+		if _, skip := rc.skipDay(d); skip {
+			return true
+		}
+	}
+	return false
+}
+
+// parseHolidays turns "2006-01-02" dates into a set.
+func parseHolidays(dates []string) (map[string]bool, error) {
+	set := make(map[string]bool, len(dates))
+	for _, d := range dates {
+		t, err := time.Parse(dateFormat, strings.TrimSpace(d))
+		if err != nil {
+			return nil, fmt.Errorf("holiday %q is not a YYYY-MM-DD date", d)
+		}
+		set[t.Format(dateFormat)] = true
+	}
+	return set, nil
+}
+
+// ParseWeekday parses an English weekday name, full or abbreviated to three letters and in
+// any case, e.g. "Saturday" or "sat".
+func ParseWeekday(s string) (time.Weekday, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := strings.ToLower(d.String())
+		if s == name || s == name[:3] {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("%q is not a weekday", s)
+}
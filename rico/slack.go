@@ -0,0 +1,82 @@
+package rico
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// slackNotifier posts events to a Slack Incoming Webhook.
+type slackNotifier struct {
+	client  *http.Client
+	url     string
+	timeout time.Duration
+	numbers numberFormat // how values are written, see WithNumberFormat
+}
+
+// slackEscaper escapes the control characters of Slack mrkdwn.
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type slackBlock struct {
+	Type string     `json:"type"`
+	Text *slackText `json:"text,omitempty"`
+}
+
+type slackMessage struct {
+	Text   string       `json:"text"` // fallback for notifications
+	Blocks []slackBlock `json:"blocks"`
+}
+
+// Notify implements Notifier.
+func (s *slackNotifier) Notify(ctx context.Context, ev Event) error {
+	var text string
+	if ev.Kind == EventRateChange {
+		buyChange, sellChange := ev.changes(s.numbers)
+		text = fmt.Sprintf("*1 %s*\nყიდვა: %s%s, გაყიდვა: %s%s",
+			slackEscaper.Replace(ev.Rate.Currency), s.numbers.number(ev.Rate.Buy), buyChange, s.numbers.number(ev.Rate.Sell), sellChange)
+		if ev.Rate.hasTransfer() {
+			text += fmt.Sprintf("\nუნაღდო: ყიდვა %s, გაყიდვა %s", s.numbers.number(ev.Rate.TransferBuy), s.numbers.number(ev.Rate.TransferSell))
+		}
+	} else {
+		text = slackEscaper.Replace(ev.Text)
+	}
+	msg := slackMessage{
+		Text:   text,
+		Blocks: []slackBlock{{Type: "section", Text: &slackText{Type: "mrkdwn", Text: text}}},
+	}
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("slack: encoding message: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("slack: creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("slack: sending message: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Slack answers a plain "ok" with 200 on success
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("slack: received non-200 status: %d", resp.StatusCode)
+	}
+	return nil
+}
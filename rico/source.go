@@ -0,0 +1,233 @@
+package rico
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	neturl "net/url"
+	"runtime/debug"
+	"strings"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// maxConcurrentFetches bounds how many sources besides the primary one are fetched at once.
+const maxConcurrentFetches = 4
+
+// Source provides exchange rates, e.g. scraped from an exchanger's website.
+type Source interface {
+	// Name identifies the source in messages.
+	Name() string
+	// Fetch returns the current rates keyed by currency code.
+	Fetch(ctx context.Context) (map[string]Rate, error)
+}
+
+// ricoSource scrapes rates from a rico.ge page.
+type ricoSource struct {
+	rc  *RateChecker
+	url string
+}
+
+// Name implements Source, naming the source after the page's host.
+func (s *ricoSource) Name() string {
+	u, err := neturl.Parse(s.url)
+	if err != nil || u.Host == "" {
+		return s.url
+	}
+	return strings.TrimPrefix(u.Hostname(), "www.")
+}
+
+// Fetch implements Source.
+func (s *ricoSource) Fetch(ctx context.Context) (map[string]Rate, error) {
+	return s.rc.scrape(ctx, s.url, s.Name())
+}
+
+// BestRate is the best offer for a currency across all sources: the highest price an
+// exchanger buys it for and the lowest price it sells it for.
+type BestRate struct {
+	Currency   string
+	Buy        float64
+	BuySource  string
+	Sell       float64
+	SellSource string
+}
+
+// bestRates picks the best offers per currency from the rates of every source.
+func bestRates(all []map[string]Rate, currencies []string) []BestRate {
+	var ret []BestRate
+	for _, currency := range currencies {
+		var best BestRate
+		for _, rates := range all {
+			r, ok := rates[currency]
+			if !ok {
+				continue
+			}
+			if best.Currency == "" {
+				best = BestRate{Currency: currency, Buy: r.Buy, BuySource: r.Source, Sell: r.Sell, SellSource: r.Source}
+				continue
+			}
+			if r.Buy > best.Buy {
+				best.Buy, best.BuySource = r.Buy, r.Source
+			}
+			if r.Sell < best.Sell {
+				best.Sell, best.SellSource = r.Sell, r.Source
+			}
+		}
+		if best.Currency != "" {
+			ret = append(ret, best)
+		}
+	}
+	return ret
+}
+
+// fetchAll fetches the primary source, the way fetch does, together with every other
+// source, up to maxConcurrentFetches of the others at a time. err is the primary source's
+// error; when it fails the others are canceled, as there's nothing to compare them with.
+// Failing other sources are left out of others and their errors joined into othersErr.
+func (rc *RateChecker) fetchAll(ctx context.Context) (primary map[string]Rate, others []map[string]Rate, othersErr, err error) {
+	if len(rc.sources) < 2 {
+		primary, err = rc.fetch(ctx)
+		return primary, nil, nil, err
+	}
+
+	results := make([]map[string]Rate, len(rc.sources)-1)
+	errs := make([]error, len(rc.sources)-1)
+
+	g, gctx := errgroup.WithContext(ctx)
+	// the primary source takes a slot of its own
+	g.SetLimit(maxConcurrentFetches + 1)
+	g.Go(func() (err error) {
+		// Run only recovers panics of its own goroutine
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("fetching %s: panic: %v", rc.sources[0].Name(), r)
+				rc.logger.Error("source panicked", "source", rc.sources[0].Name(), "panic", r, "stack", string(debug.Stack()))
+			}
+		}()
+		primary, err = rc.fetch(gctx)
+		return err
+	})
+	for i, src := range rc.sources[1:] {
+		g.Go(func() error {
+			defer func() {
+				if r := recover(); r != nil {
+					errs[i] = fmt.Errorf("fetching %s: panic: %v", src.Name(), r)
+					rc.logger.Error("source panicked", "source", src.Name(), "panic", r, "stack", string(debug.Stack()))
+				}
+			}()
+			if err := gctx.Err(); err != nil {
+				errs[i] = fmt.Errorf("fetching %s: %w", src.Name(), err)
+				return nil
+			}
+			rates, err := src.Fetch(gctx)
+			if err != nil {
+				errs[i] = fmt.Errorf("fetching %s: %w", src.Name(), err)
+				return nil
+			}
+			for currency, r := range rates {
+				if r.Source == "" {
+					r.Source = src.Name()
+				}
+				rates[currency] = r.rounded(rc.precision)
+			}
+			results[i] = rates
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, nil, nil, err
+	}
+
+	// keep the order of the sources, so ties go the same way on every check
+	for _, rates := range results {
+		if rates != nil {
+			others = append(others, rates)
+		}
+	}
+	return primary, others, errors.Join(errs...), nil
+}
+
+// compareSources alerts whenever the best offer for a watched currency changes between
+// the primary source's rates and the others'. It does nothing with a single source.
+func (rc *RateChecker) compareSources(ctx context.Context, primary map[string]Rate, others []map[string]Rate) error {
+	if len(rc.sources) < 2 {
+		return nil
+	}
+
+	all := append([]map[string]Rate{primary}, others...)
+	errs := []error{rc.checkArbitrage(ctx, all)}
+	for _, best := range bestRates(all, rc.currencies) {
+		if rc.best[best.Currency] == best {
+			continue
+		}
+		rc.best[best.Currency] = best
+		text := fmt.Sprintf("🏆 best %s: buy %s at %s, sell %s at %s",
+			best.Currency, rc.number(best.Buy), best.BuySource, rc.number(best.Sell), best.SellSource)
+		if err := rc.alert(ctx, best.Currency, text); err != nil {
+			errs = append(errs, fmt.Errorf("sending best %s rate: %w", best.Currency, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Arbitrage is a riskless spread: one source buys a currency for more than another sells it.
+type Arbitrage struct {
+	Currency  string
+	BuyFrom   string  // source selling the currency cheaply
+	BuyPrice  float64 // its sell rate
+	SellTo    string  // source buying the currency expensively
+	SellPrice float64 // its buy rate
+}
+
+// Spread returns the profit per unit of the opportunity.
+func (a Arbitrage) Spread() float64 {
+	return a.SellPrice - a.BuyPrice
+}
+
+// findArbitrage lists every pair of sources where one buys a watched currency for more
+// than the other sells it.
+func findArbitrage(all []map[string]Rate, currencies []string) []Arbitrage {
+	var ret []Arbitrage
+	for _, currency := range currencies {
+		for i, a := range all {
+			for j, b := range all {
+				if i == j {
+					continue
+				}
+				seller, ok1 := a[currency]
+				buyer, ok2 := b[currency]
+				if ok1 && ok2 && buyer.Buy > seller.Sell {
+					ret = append(ret, Arbitrage{
+						Currency:  currency,
+						BuyFrom:   seller.Source,
+						BuyPrice:  seller.Sell,
+						SellTo:    buyer.Source,
+						SellPrice: buyer.Buy,
+					})
+				}
+			}
+		}
+	}
+	return ret
+}
+
+// checkArbitrage alerts about arbitrage opportunities between sources. Each opportunity
+// is alerted once when it opens and again only after it closed in between.
+func (rc *RateChecker) checkArbitrage(ctx context.Context, all []map[string]Rate) error {
+	open := make(map[string]bool)
+	var errs []error
+	for _, a := range findArbitrage(all, rc.currencies) {
+		key := a.Currency + ":" + a.BuyFrom + ">" + a.SellTo
+		open[key] = true
+		if rc.arbitrage[key] {
+			continue
+		}
+		text := fmt.Sprintf("💰 %s arbitrage: buy at %s for %s, sell at %s for %s, spread %s",
+			a.Currency, a.BuyFrom, rc.number(a.BuyPrice), a.SellTo, rc.number(a.SellPrice), rc.number(a.Spread()))
+		if err := rc.alert(ctx, a.Currency, text); err != nil {
+			errs = append(errs, fmt.Errorf("sending %s arbitrage alert: %w", a.Currency, err))
+		}
+	}
+	rc.arbitrage = open
+	return errors.Join(errs...)
+}
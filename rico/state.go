@@ -0,0 +1,63 @@
+package rico
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// loadState restores the last rates from the state file, so the first check after a restart
+// compares against them instead of reporting every rate as new. A missing or unreadable
+// file starts fresh.
+func (rc *RateChecker) loadState() {
+	b, err := os.ReadFile(rc.statePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	if err != nil {
+		rc.logger.Warn("reading state file, starting fresh", "path", rc.statePath, "error", err)
+		return
+	}
+	var rates map[string]Rate
+	if err := json.Unmarshal(b, &rates); err != nil {
+		rc.logger.Warn("state file is corrupt, starting fresh", "path", rc.statePath, "error", err)
+		return
+	}
+	for _, currency := range rc.currencies {
+		if r, ok := rates[currency]; ok && r.Currency == currency {
+			rc.rates[currency] = r
+		}
+	}
+	rc.logger.Info("restored last rates", "path", rc.statePath, "currencies", len(rc.rates))
+}
+
+// saveState writes the last rates to the state file. It writes a temporary file first and
+// renames it over the old one, so a crash midway can't leave a truncated file behind.
+func (rc *RateChecker) saveState() error {
+	rc.mu.RLock()
+	b, err := json.MarshalIndent(rc.rates, "", "  ")
+	rc.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("encoding state: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(rc.statePath), filepath.Base(rc.statePath)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("writing state: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // fails harmlessly once renamed
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing state: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), rc.statePath); err != nil {
+		return fmt.Errorf("writing state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package rico
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite" // registers the "sqlite" database/sql driver
+)
+
+// RateRecord is a rate observed at a point in time.
+type RateRecord struct {
+	Rate
+	Time time.Time
+}
+
+// Store persists observed rates.
+type Store interface {
+	// SaveRate records rate as observed at the given time.
+	SaveRate(ctx context.Context, rate Rate, at time.Time) error
+	// LastRates returns up to n most recently saved rates, newest first.
+	LastRates(ctx context.Context, n int) ([]RateRecord, error)
+}
+
+// SQLiteStore is a Store backed by an SQLite database file.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore opens (or creates) the SQLite database at path and prepares its schema.
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("opening sqlite database: %w", err)
+	}
+
+	const schema = `CREATE TABLE IF NOT EXISTS rates (
+		id            INTEGER PRIMARY KEY AUTOINCREMENT,
+		currency      TEXT    NOT NULL,
+		buy           REAL    NOT NULL,
+		sell          REAL    NOT NULL,
+		observed_at   INTEGER NOT NULL,
+		transfer_buy  REAL    NOT NULL DEFAULT 0, -- 0 when the page has no transfer rates
+		transfer_sell REAL    NOT NULL DEFAULT 0
+	)`
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("creating rates table: %w", err)
+	}
+	if err := addTransferColumns(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	const subscriptionsSchema = `CREATE TABLE IF NOT EXISTS subscriptions (
+		chat_id    TEXT PRIMARY KEY,
+		currencies TEXT NOT NULL -- comma-separated, empty for all
+	)`
+	if _, err := db.Exec(subscriptionsSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("creating subscriptions table: %w", err)
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+// addTransferColumns adds the transfer rate columns to a rates table created before they
+// existed. Rates saved before then read back without transfer rates.
+func addTransferColumns(db *sql.DB) error {
+	rows, err := db.Query(`SELECT name FROM pragma_table_info('rates')`)
+	if err != nil {
+		return fmt.Errorf("reading rates table columns: %w", err)
+	}
+	defer rows.Close()
+
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return fmt.Errorf("scanning rates table column: %w", err)
+		}
+		columns[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("reading rates table columns: %w", err)
+	}
+
+	for _, column := range []string{"transfer_buy", "transfer_sell"} {
+		if columns[column] {
+			continue
+		}
+		if _, err := db.Exec(`ALTER TABLE rates ADD COLUMN ` + column + ` REAL NOT NULL DEFAULT 0`); err != nil {
+			return fmt.Errorf("adding %s column to rates table: %w", column, err)
+		}
+	}
+	return nil
+}
+
+// SaveRate implements Store.
+func (s *SQLiteStore) SaveRate(ctx context.Context, rate Rate, at time.Time) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO rates (currency, buy, sell, transfer_buy, transfer_sell, observed_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		rate.Currency, rate.Buy, rate.Sell, rate.TransferBuy, rate.TransferSell, at.UnixNano())
+	if err != nil {
+		return fmt.Errorf("inserting rate: %w", err)
+	}
+	return nil
+}
+
+// LastRates implements Store.
+func (s *SQLiteStore) LastRates(ctx context.Context, n int) ([]RateRecord, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT currency, buy, sell, transfer_buy, transfer_sell, observed_at FROM rates ORDER BY observed_at DESC, id DESC LIMIT ?`, n)
+	if err != nil {
+		return nil, fmt.Errorf("querying rates: %w", err)
+	}
+	defer rows.Close()
+
+	var ret []RateRecord
+	for rows.Next() {
+		var (
+			rec RateRecord
+			at  int64
+		)
+		if err := rows.Scan(&rec.Currency, &rec.Buy, &rec.Sell, &rec.TransferBuy, &rec.TransferSell, &at); err != nil {
+			return nil, fmt.Errorf("scanning rate: %w", err)
+		}
+		rec.Time = time.Unix(0, at)
+		ret = append(ret, rec)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading rates: %w", err)
+	}
+	return ret, nil
+}
+
+// Extremes implements ExtremesStore.
+func (s *SQLiteStore) Extremes(ctx context.Context, currency string) (Extremes, bool, error) {
+	e := Extremes{Currency: currency}
+	for _, q := range []struct {
+		dst   *Extreme
+		query string
+	}{
+		{&e.HighBuy, `SELECT buy, observed_at FROM rates WHERE currency = ? ORDER BY buy DESC, observed_at LIMIT 1`},
+		{&e.LowBuy, `SELECT buy, observed_at FROM rates WHERE currency = ? ORDER BY buy ASC, observed_at LIMIT 1`},
+		{&e.HighSell, `SELECT sell, observed_at FROM rates WHERE currency = ? ORDER BY sell DESC, observed_at LIMIT 1`},
+		{&e.LowSell, `SELECT sell, observed_at FROM rates WHERE currency = ? ORDER BY sell ASC, observed_at LIMIT 1`},
+	} {
+		var at int64
+		err := s.db.QueryRowContext(ctx, q.query, currency).Scan(&q.dst.Value, &at)
+		if errors.Is(err, sql.ErrNoRows) {
+			return Extremes{}, false, nil
+		}
+		if err != nil {
+			return Extremes{}, false, fmt.Errorf("querying extremes: %w", err)
+		}
+		q.dst.Time = time.Unix(0, at)
+	}
+	return e, true, nil
+}
+
+// Subscriptions implements SubscriptionStore.
+func (s *SQLiteStore) Subscriptions(ctx context.Context) (map[string][]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT chat_id, currencies FROM subscriptions`)
+	if err != nil {
+		return nil, fmt.Errorf("querying subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	ret := make(map[string][]string)
+	for rows.Next() {
+		var chatID, currencies string
+		if err := rows.Scan(&chatID, &currencies); err != nil {
+			return nil, fmt.Errorf("scanning subscription: %w", err)
+		}
+		var subs []string
+		if currencies != "" {
+			subs = strings.Split(currencies, ",")
+		}
+		ret[chatID] = subs
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading subscriptions: %w", err)
+	}
+	return ret, nil
+}
+
+// SetSubscription implements SubscriptionStore.
+func (s *SQLiteStore) SetSubscription(ctx context.Context, chatID string, currencies []string) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO subscriptions (chat_id, currencies) VALUES (?, ?)
+		ON CONFLICT (chat_id) DO UPDATE SET currencies = excluded.currencies`,
+		chatID, strings.Join(currencies, ","))
+	if err != nil {
+		return fmt.Errorf("saving subscription: %w", err)
+	}
+	return nil
+}
+
+// DeleteSubscription implements SubscriptionStore.
+func (s *SQLiteStore) DeleteSubscription(ctx context.Context, chatID string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM subscriptions WHERE chat_id = ?`, chatID); err != nil {
+		return fmt.Errorf("deleting subscription: %w", err)
+	}
+	return nil
+}
+
+// Close closes the underlying database.
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
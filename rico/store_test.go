@@ -0,0 +1,65 @@
+package rico
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSQLiteStoreTransferRates(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "rates.db")
+
+	// a database from before the transfer rate columns existed
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`CREATE TABLE rates (
+		id          INTEGER PRIMARY KEY AUTOINCREMENT,
+		currency    TEXT    NOT NULL,
+		buy         REAL    NOT NULL,
+		sell        REAL    NOT NULL,
+		observed_at INTEGER NOT NULL
+	)`)
+	if err == nil {
+		_, err = db.Exec(`INSERT INTO rates (currency, buy, sell, observed_at) VALUES ('USD', 2.7, 2.72, 1)`)
+	}
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewSQLiteStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	want := Rate{Currency: "USD", Buy: 2.71, Sell: 2.73, TransferBuy: 2.705, TransferSell: 2.725}
+	if err := s.SaveRate(ctx, want, time.Unix(0, 2)); err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.LastRates(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("LastRates returned %d rates, want 2", len(got))
+	}
+	if got[0].Rate != want {
+		t.Errorf("saved rate reads back as %+v, want %+v", got[0].Rate, want)
+	}
+	if old := (Rate{Currency: "USD", Buy: 2.7, Sell: 2.72}); got[1].Rate != old {
+		t.Errorf("rate saved before the migration reads back as %+v, want %+v", got[1].Rate, old)
+	}
+
+	// opening it again doesn't try to add the columns twice
+	s2, err := NewSQLiteStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s2.Close()
+}
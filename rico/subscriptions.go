@@ -0,0 +1,154 @@
+package rico
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// SubscriptionStore is a Store that also persists which currencies each Telegram chat
+// subscribed to with /subscribe. SQLiteStore implements it.
+type SubscriptionStore interface {
+	Store
+	// Subscriptions returns the currencies per chat ID; an empty list means all of them.
+	Subscriptions(ctx context.Context) (map[string][]string, error)
+	// SetSubscription replaces the currencies chatID is subscribed to.
+	SetSubscription(ctx context.Context, chatID string, currencies []string) error
+	// DeleteSubscription removes the subscription of chatID.
+	DeleteSubscription(ctx context.Context, chatID string) error
+}
+
+// loadSubscriptions replaces the in-memory subscriptions with the store's, if it keeps them.
+func (rc *RateChecker) loadSubscriptions(ctx context.Context) error {
+	s, ok := rc.store.(SubscriptionStore)
+	if !ok {
+		return nil
+	}
+	subs, err := s.Subscriptions(ctx)
+	if err != nil {
+		return fmt.Errorf("loading subscriptions: %w", err)
+	}
+
+	rc.subsMu.Lock()
+	defer rc.subsMu.Unlock()
+	rc.subscriptions = subs
+	return nil
+}
+
+// subscribe adds currencies to the subscription of chatID, narrowing an all-currency
+// subscription down to them; no currencies subscribes it to all of them. It returns the
+// resulting subscription.
+func (rc *RateChecker) subscribe(ctx context.Context, chatID string, currencies []string) ([]string, error) {
+	rc.subsMu.Lock()
+	defer rc.subsMu.Unlock()
+
+	var next []string
+	if len(currencies) > 0 {
+		next = append(slices.Clone(rc.subscriptions[chatID]), currencies...)
+		sort.Strings(next)
+		next = slices.Compact(next)
+	}
+
+	if s, ok := rc.store.(SubscriptionStore); ok {
+		if err := s.SetSubscription(ctx, chatID, next); err != nil {
+			return nil, fmt.Errorf("saving subscription: %w", err)
+		}
+	}
+	rc.subscriptions[chatID] = next
+	return next, nil
+}
+
+// unsubscribe removes the subscription of chatID. Configured channels go back to getting
+// every currency, other chats stop getting messages.
+func (rc *RateChecker) unsubscribe(ctx context.Context, chatID string) error {
+	rc.subsMu.Lock()
+	defer rc.subsMu.Unlock()
+
+	if s, ok := rc.store.(SubscriptionStore); ok {
+		if err := s.DeleteSubscription(ctx, chatID); err != nil {
+			return fmt.Errorf("deleting subscription: %w", err)
+		}
+	}
+	delete(rc.subscriptions, chatID)
+	return nil
+}
+
+// recipients returns the Telegram chats ev goes to. Configured channels get everything
+// unless they subscribed to specific currencies; other chats only get the rate changes
+// and alerts of the currencies they subscribed to. Batched changes go to every chat
+// subscribed to at least one of their currencies, narrowed down by narrow.
+func (rc *RateChecker) recipients(ev Event) []string {
+	rc.subsMu.RLock()
+	defer rc.subsMu.RUnlock()
+
+	currencies := ev.currencies()
+	var ret []string
+	for _, id := range rc.telegram.channelIDs {
+		if subs, ok := rc.subscriptions[id]; ok && len(currencies) > 0 && !subscribedTo(subs, currencies) {
+			continue
+		}
+		ret = append(ret, id)
+	}
+	if len(currencies) == 0 {
+		return ret
+	}
+
+	var others []string
+	for id, subs := range rc.subscriptions {
+		if !slices.Contains(rc.telegram.channelIDs, id) && subscribedTo(subs, currencies) {
+			others = append(others, id)
+		}
+	}
+	sort.Strings(others)
+	return append(ret, others...)
+}
+
+// narrow returns ev as chatID gets it: batched changes keep only the currencies chatID
+// subscribed to and are rendered again. Other events are the same for every chat.
+func (rc *RateChecker) narrow(ev Event, chatID string) Event {
+	if ev.Kind != EventRateChanges {
+		return ev
+	}
+	rc.subsMu.RLock()
+	subs := rc.subscriptions[chatID]
+	rc.subsMu.RUnlock()
+	if len(subs) == 0 {
+		return ev
+	}
+
+	var changes []Event
+	for _, c := range ev.Changes {
+		if slices.Contains(subs, c.Rate.Currency) {
+			changes = append(changes, c)
+		}
+	}
+	if len(changes) == len(ev.Changes) {
+		return ev
+	}
+	ev.Changes, ev.Text = changes, rc.formatBatch(changes, ev.Time)
+	return ev
+}
+
+// subscribedTo reports whether subs covers any of currencies; an empty subscription
+// covers all.
+func subscribedTo(subs []string, currencies []string) bool {
+	if len(subs) == 0 {
+		return true
+	}
+	for _, c := range currencies {
+		if slices.Contains(subs, c) {
+			return true
+		}
+	}
+	return false
+}
+
+// describeSubscription renders subs for a command reply.
+func describeSubscription(subs []string) string {
+	if len(subs) == 0 {
+		return "all currencies"
+	}
+	return strings.Join(subs, ", ")
+}
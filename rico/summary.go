@@ -0,0 +1,119 @@
+package rico
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// OHLC holds the first, highest, lowest and last value seen over a period.
+type OHLC struct {
+	Open  float64
+	High  float64
+	Low   float64
+	Close float64
+}
+
+func newOHLC(v float64) OHLC {
+	return OHLC{Open: v, High: v, Low: v, Close: v}
+}
+
+func (o *OHLC) add(v float64) {
+	o.High = max(o.High, v)
+	o.Low = min(o.Low, v)
+	o.Close = v
+}
+
+// DailySummary aggregates the rates of one currency observed during a calendar day.
+type DailySummary struct {
+	Currency string
+	Day      time.Time // midnight in the checker's location
+	Buy      OHLC
+	Sell     OHLC
+}
+
+// startOfDay returns midnight of t's calendar day in loc. Days are compared by their
+// date rather than by 24 hour steps, so DST transitions don't shift the boundary.
+func startOfDay(t time.Time, loc *time.Location) time.Time {
+	y, m, d := t.In(loc).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, loc)
+}
+
+// recordDaily adds rate observed at the given time to the running daily summary.
+// When at falls on a new day, the summary of the finished day is returned.
+func (rc *RateChecker) recordDaily(rate Rate, at time.Time) (finished *DailySummary) {
+	day := startOfDay(at, rc.location)
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	cur, ok := rc.daily[rate.Currency]
+	if ok && cur.Day.Equal(day) {
+		cur.Buy.add(rate.Buy)
+		cur.Sell.add(rate.Sell)
+		rc.daily[rate.Currency] = cur
+		return nil
+	}
+
+	rc.daily[rate.Currency] = DailySummary{
+		Currency: rate.Currency,
+		Day:      day,
+		Buy:      newOHLC(rate.Buy),
+		Sell:     newOHLC(rate.Sell),
+	}
+	if ok {
+		return &cur
+	}
+	return nil
+}
+
+// DailySummaries returns the running summaries of the current day, one per watched
+// currency seen so far.
+func (rc *RateChecker) DailySummaries() []DailySummary {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+
+	var ret []DailySummary
+	for _, currency := range rc.currencies {
+		if s, ok := rc.daily[currency]; ok {
+			ret = append(ret, s)
+		}
+	}
+	return ret
+}
+
+// SendDailySummary sends the running summaries of the current day to the notifiers.
+// With WithDailySummary the summary of each finished day is sent automatically on the
+// first check after midnight.
+func (rc *RateChecker) SendDailySummary(ctx context.Context) error {
+	var errs []error
+	for _, s := range rc.DailySummaries() {
+		// asked for explicitly, so it isn't held back during the quiet hours
+		ev := Event{Kind: EventAlert, Currency: s.Currency, Text: rc.formatSummary(s), Time: rc.clock.Now()}
+		if err := rc.notify(ctx, ev); err != nil {
+			errs = append(errs, fmt.Errorf("sending %s summary: %w", s.Currency, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// formatSummary renders s as a plain text message.
+func (rc *RateChecker) formatSummary(s DailySummary) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s", s.Day.Format("Jan 2"), s.Currency)
+	for _, row := range []struct {
+		label string
+		v     OHLC
+	}{{"ყიდვა", s.Buy}, {"გაყიდვა", s.Sell}} {
+		fmt.Fprintf(&b, "\n%s: open %s, high %s, low %s, close %s",
+			row.label, rc.number(row.v.Open), rc.number(row.v.High), rc.number(row.v.Low), rc.number(row.v.Close))
+	}
+	if e, ok := rc.Extremes()[s.Currency]; ok {
+		fmt.Fprintf(&b, "\nall-time: highest გაყიდვა %s (%s), lowest ყიდვა %s (%s)",
+			rc.number(e.HighSell.Value), e.HighSell.Time.In(rc.location).Format(rc.timeFormat),
+			rc.number(e.LowBuy.Value), e.LowBuy.Time.In(rc.location).Format(rc.timeFormat))
+	}
+	return b.String()
+}
@@ -0,0 +1,417 @@
+package rico
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+)
+
+// maxMessageLength is the longest message text Telegram accepts, in characters.
+const maxMessageLength = 4096
+
+// defaultAuthFailureLimit is how many times in a row Telegram may reject the bot token
+// before sending stops.
+const defaultAuthFailureLimit = 3
+
+// ErrTelegramUnauthorized is returned when Telegram rejects the bot token, e.g. because
+// it was revoked.
+var ErrTelegramUnauthorized = errors.New("telegram rejected the bot token")
+
+// telegramNotifier sends events as messages to Telegram channels.
+type telegramNotifier struct {
+	client     *http.Client
+	botTokens  []string // the primary token first, then the backups in order
+	channelIDs []string
+	parseMode  ParseMode
+	timeout    time.Duration // per request
+	attempts   int           // sends tried per channel while rate limited
+	authLimit  int           // auth failures in a row per token before giving up; 0 never gives up
+	logger     *slog.Logger
+	render     func(Event) (string, error)
+	recipients func(Event) []string      // chats an event goes to; nil sends it to channelIDs
+	narrow     func(Event, string) Event // ev as the chat with the given ID gets it; nil sends ev as is
+	bigMoveIDs []string                  // chats big move alerts go to instead, see BigMove
+
+	current      atomic.Int32  // index of the token in use, the last one Telegram accepted
+	authFailures atomic.Int32  // auth failures in a row, reset by any accepted request
+	revoked      chan struct{} // closed once authLimit is reached
+	revokeOnce   sync.Once
+}
+
+// Notify implements Notifier by sending the rendered event to every configured channel,
+// or to the chats picked by recipients, narrowed down for each of them. Big move alerts go
+// to their own chats when set.
+func (t *telegramNotifier) Notify(ctx context.Context, ev Event) error {
+	if t.disabled() {
+		// already logged when it happened, repeating it every check would only be noise
+		return nil
+	}
+	messageText, err := t.render(ev)
+	if err != nil {
+		return fmt.Errorf("telegram: %w", err)
+	}
+
+	// a failing or throttled channel must not keep the message from reaching the others
+	channelIDs := t.channelIDs
+	switch {
+	case ev.Kind == EventBigMove && len(t.bigMoveIDs) > 0:
+		channelIDs = t.bigMoveIDs
+	case t.recipients != nil:
+		channelIDs = t.recipients(ev)
+	}
+	var errs []error
+	for _, channelID := range channelIDs {
+		text := messageText
+		if t.narrow != nil {
+			if narrowed := t.narrow(ev, channelID); len(narrowed.Changes) != len(ev.Changes) {
+				if text, err = t.render(narrowed); err != nil {
+					errs = append(errs, fmt.Errorf("telegram channel %s: %w", channelID, err))
+					continue
+				}
+			}
+		}
+		if err := t.sendToChannel(ctx, channelID, text); err != nil {
+			errs = append(errs, fmt.Errorf("telegram channel %s: %w", channelID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// telegramResponse is the envelope of every Bot API response.
+type telegramResponse struct {
+	OK          bool   `json:"ok"`
+	Description string `json:"description"`
+	Parameters  struct {
+		RetryAfter int `json:"retry_after"` // seconds to wait when rate limited
+	} `json:"parameters"`
+}
+
+// sendToChannel posts messageText to a single Telegram channel, split into several messages
+// when it's longer than Telegram allows. When Telegram rate limits the bot it waits as long
+// as told to and tries again, up to the configured attempts.
+func (t *telegramNotifier) sendToChannel(ctx context.Context, channelID, messageText string) error {
+	for _, part := range splitMessage(messageText, maxMessageLength, t.parseMode) {
+		if err := t.sendPart(ctx, channelID, part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendPart posts a message that fits Telegram's length limit. When Telegram rejects the
+// token or rate limits it, the next backup token is tried right away, once per token.
+func (t *telegramNotifier) sendPart(ctx context.Context, channelID, messageText string) error {
+	switches := 0
+	for attempt := 1; ; attempt++ {
+		token := t.current.Load()
+		retryAfter, err := t.postMessage(ctx, t.botTokens[token], channelID, messageText)
+		if err == nil {
+			t.logger.Info("message sent", "channel", channelID, "text", messageText)
+			return nil
+		}
+		if (retryAfter > 0 || errors.Is(err, ErrTelegramUnauthorized)) && switches < len(t.botTokens)-1 && t.failover(token, err) {
+			// trying another token doesn't use up an attempt
+			switches++
+			attempt--
+			continue
+		}
+		if retryAfter == 0 || attempt >= t.attempts {
+			return err
+		}
+
+		t.logger.Warn("rate limited by telegram, waiting", "channel", channelID, "retry_after", retryAfter, "attempt", attempt)
+		if err := sleep(ctx, retryAfter); err != nil {
+			return fmt.Errorf("waiting out telegram rate limit: %w", err)
+		}
+	}
+}
+
+// postMessage makes a single sendMessage call. Errors carry Telegram's description of the
+// problem; retryAfter is set when Telegram answered 429 Too Many Requests.
+func (t *telegramNotifier) postMessage(ctx context.Context, token, channelID, messageText string) (retryAfter time.Duration, err error) {
+	if t.disabled() {
+		return 0, ErrTelegramUnauthorized
+	}
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	form := url.Values{}
+	form.Set("chat_id", channelID)
+	form.Set("text", messageText)
+	if t.parseMode != ParseModePlain {
+		form.Set("parse_mode", string(t.parseMode))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint(token, "sendMessage"), strings.NewReader(form.Encode()))
+	if err != nil {
+		return 0, fmt.Errorf("creating telegram request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("sending telegram message: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		// the body explains what went wrong, e.g. "Bad Request: chat not found"
+		var body telegramResponse
+		decodeErr := json.NewDecoder(resp.Body).Decode(&body)
+		if err := t.checkAuth(resp.StatusCode, body.Description); err != nil {
+			return 0, err
+		}
+		if decodeErr != nil || body.Description == "" {
+			return 0, fmt.Errorf("received non-200 status from telegram: %d", resp.StatusCode)
+		}
+		if resp.StatusCode == http.StatusTooManyRequests && body.Parameters.RetryAfter > 0 {
+			retryAfter = time.Duration(body.Parameters.RetryAfter) * time.Second
+		}
+		return retryAfter, fmt.Errorf("telegram error %d: %s", resp.StatusCode, body.Description)
+	}
+	t.authFailures.Store(0)
+	return 0, nil
+}
+
+// checkAuth returns ErrTelegramUnauthorized when a response means the bot token itself is
+// no good and counts the failure. Telegram answers 401 Unauthorized for a revoked token and
+// 404 Not Found for one it doesn't know at all. 403 Forbidden only concerns a single chat
+// the bot was removed from, so it doesn't count. With backup tokens, sending stops once the
+// failures add up to the limit for every token.
+func (t *telegramNotifier) checkAuth(status int, description string) error {
+	if status != http.StatusUnauthorized && (status != http.StatusNotFound || description != "Not Found") {
+		return nil
+	}
+	n := t.authFailures.Add(1)
+	if t.authLimit > 0 && int(n) >= t.authLimit*len(t.botTokens) {
+		t.revokeOnce.Do(func() {
+			t.logger.Error("telegram keeps rejecting the bot token, giving up on telegram until restarted with a new one", "failures", n, "description", description)
+			close(t.revoked)
+		})
+	}
+	return fmt.Errorf("%w (status %d): %s", ErrTelegramUnauthorized, status, description)
+}
+
+// failover switches from the token at index failed to the next one after Telegram refused a
+// request made with it. It reports whether another token is in use now, which may also be
+// because a concurrent request switched already.
+func (t *telegramNotifier) failover(failed int32, cause error) bool {
+	if len(t.botTokens) < 2 || t.disabled() {
+		return false
+	}
+	next := (failed + 1) % int32(len(t.botTokens))
+	if t.current.CompareAndSwap(failed, next) {
+		t.logger.Warn("switching to another bot token", "token", next+1, "tokens", len(t.botTokens), "error", cause)
+	}
+	return true
+}
+
+// disabled reports whether sending was given up on because the token was rejected.
+func (t *telegramNotifier) disabled() bool {
+	select {
+	case <-t.revoked:
+		return true
+	default:
+		return false
+	}
+}
+
+// splitMessage splits text into parts of at most limit characters. It splits between lines,
+// which the message templates never open a bold or link across, so every part stays valid
+// in the message's parse mode. Only a single line longer than limit is cut in the middle,
+// where cutPoint finds it's safe to in mode.
+func splitMessage(text string, limit int, mode ParseMode) []string {
+	if utf8.RuneCountInString(text) <= limit {
+		return []string{text}
+	}
+
+	var (
+		parts []string
+		cur   strings.Builder
+		n     int // characters in cur
+	)
+	flush := func() {
+		if n > 0 {
+			parts = append(parts, cur.String())
+			cur.Reset()
+			n = 0
+		}
+	}
+	for _, line := range strings.SplitAfter(text, "\n") {
+		// a line break ending a part is dropped, Telegram trims it anyway
+		l := utf8.RuneCountInString(strings.TrimSuffix(line, "\n"))
+		if n > 0 && n+l > limit {
+			flush()
+		}
+		for l > limit {
+			runes := []rune(line)
+			cut := cutPoint(runes, limit, mode)
+			parts = append(parts, string(runes[:cut]))
+			line, l = string(runes[cut:]), l-cut
+		}
+		cur.WriteString(line)
+		n += utf8.RuneCountInString(line)
+	}
+	flush()
+	for i, p := range parts {
+		parts[i] = strings.TrimSuffix(p, "\n")
+	}
+	return parts
+}
+
+// cutPoint returns where to cut line, which is longer than limit characters, so that
+// the part before the cut fits: the last place up to limit outside any escape sequence,
+// entity and markup of mode, so Telegram accepts both parts. Failing that it settles for
+// a place outside an escape sequence, tag or HTML entity, and then for limit.
+func cutPoint(line []rune, limit int, mode ParseMode) int {
+	var (
+		clean, outside int
+
+		// MarkdownV2
+		escaped bool
+		toggled = make(map[rune]bool) // markers like * opened and not closed yet
+		open    int                   // how many of them
+		link    int                   // 0 outside a link, 1 in its text, 2 right after "]", 3 in its URL
+
+		// HTML
+		inTag, inEntity bool
+		tag             []rune
+		depth           int // elements opened and not closed yet
+	)
+	for i := 0; i <= limit; i++ {
+		// i is the place right before line[i]
+		switch mode {
+		case ParseModeMarkdownV2:
+			if !escaped {
+				outside = i
+				if link == 0 && open == 0 {
+					clean = i
+				}
+			}
+		case ParseModeHTML:
+			if !inTag && !inEntity {
+				outside = i
+				if depth == 0 {
+					clean = i
+				}
+			}
+		default:
+			clean = i
+		}
+		if i == limit {
+			break
+		}
+
+		r := line[i]
+		switch mode {
+		case ParseModeMarkdownV2:
+			if escaped {
+				escaped = false
+				continue
+			}
+			if link == 2 {
+				if r == '(' {
+					link = 3
+					continue
+				}
+				link = 0
+			}
+			switch r {
+			case '\\':
+				escaped = true
+			case '[':
+				if link == 0 {
+					link = 1
+				}
+			case ']':
+				if link == 1 {
+					link = 2
+				}
+			case ')':
+				if link == 3 {
+					link = 0
+				}
+			case '*', '_', '~', '|', '`':
+				if link == 3 {
+					break
+				}
+				if toggled[r] = !toggled[r]; toggled[r] {
+					open++
+				} else {
+					open--
+				}
+			}
+		case ParseModeHTML:
+			switch {
+			case inTag && r == '>':
+				inTag = false
+				switch {
+				case len(tag) > 0 && tag[0] == '/':
+					depth--
+				case len(tag) == 0 || tag[len(tag)-1] != '/':
+					depth++
+				}
+			case inTag:
+				tag = append(tag, r)
+			case inEntity:
+				inEntity = r != ';'
+			case r == '<':
+				inTag, tag = true, tag[:0]
+			case r == '&':
+				inEntity = true
+			}
+		}
+	}
+	switch {
+	case clean > 0:
+		return clean
+	case outside > 0:
+		return outside
+	}
+	return limit
+}
+
+// TokenRevoked returns a channel that is closed once Telegram rejected the bot token, and
+// any backup tokens, as often as WithAuthFailureLimit allows and sending to Telegram stopped. It's never closed
+// without Telegram configured.
+func (rc *RateChecker) TokenRevoked() <-chan struct{} {
+	if rc.telegram == nil {
+		return nil
+	}
+	return rc.telegram.revoked
+}
+
+// endpoint returns the URL of the Bot API method called with token.
+func (t *telegramNotifier) endpoint(token, method string) string {
+	return fmt.Sprintf("https://api.telegram.org/bot%s/%s", token, method)
+}
+
+// validateTelegram checks that botToken looks like "123456:ABC-def" and that every
+// channel ID is either numeric or an @channelname.
+func validateTelegram(botToken string, channelIDs []string) error {
+	if botToken == "" {
+		return fmt.Errorf("%w: empty", ErrInvalidBotToken)
+	}
+	if !botTokenPattern.MatchString(botToken) {
+		return fmt.Errorf("%w: expected <bot id>:<secret>", ErrInvalidBotToken)
+	}
+
+	if len(channelIDs) == 0 {
+		return fmt.Errorf("%w: no channel configured", ErrInvalidChannelID)
+	}
+	for _, id := range channelIDs {
+		if !channelIDPattern.MatchString(id) {
+			return fmt.Errorf("%w: %q is neither a numeric ID nor an @channelname", ErrInvalidChannelID, id)
+		}
+	}
+	return nil
+}
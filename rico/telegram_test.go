@@ -0,0 +1,71 @@
+package rico
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSplitMessageCutsLongLinesSafely(t *testing.T) {
+	tests := []struct {
+		name  string
+		mode  ParseMode
+		line  string
+		limit int
+		first string // the part before the cut
+	}{
+		{"escape sequence", ParseModeMarkdownV2, `aaaaaaaaa\.bbbbbbbbbb`, 10, "aaaaaaaaa"},
+		{"bold", ParseModeMarkdownV2, `aaaaaa*bold*bbbbbbbbbb`, 10, "aaaaaa"},
+		{"link", ParseModeMarkdownV2, `aaa[rico](https://rico.ge/a\)b)bbbbbbbb`, 20, "aaa"},
+		{"escaped marker", ParseModeMarkdownV2, `aaaaaa\*bbbbbbbbbbbb`, 10, `aaaaaa\*bb`},
+		{"entity", ParseModeHTML, "aaaaaaaa&amp;bbbbbbbb", 10, "aaaaaaaa"},
+		{"element", ParseModeHTML, "aaaa<b>bold</b>bbbbbbbb", 10, "aaaa"},
+		{"plain", ParseModePlain, `aaaaaaaaa\.bbbbbbbbbb`, 10, `aaaaaaaaa\`},
+		{"runes", ParseModeMarkdownV2, strings.Repeat("ყიდვა", 3), 4, "ყიდვ"},
+	}
+	for _, tt := range tests {
+		parts := splitMessage(tt.line, tt.limit, tt.mode)
+		if len(parts) < 2 || parts[0] != tt.first {
+			t.Errorf("%s: split into %q, want %q first", tt.name, parts, tt.first)
+		}
+		if joined := strings.Join(parts, ""); joined != tt.line {
+			t.Errorf("%s: parts join to %q, want %q", tt.name, joined, tt.line)
+		}
+	}
+}
+
+func TestSplitMessageLongEscapedLine(t *testing.T) {
+	mode := ParseModeMarkdownV2
+	line := strings.Repeat(mode.bold("ყიდვა:")+" "+mode.escape("2.7000 (+0.4%) - ")+mode.link("rico.ge", "https://rico.ge/")+" ", 200)
+	parts := splitMessage(line, maxMessageLength, mode)
+	if len(parts) < 2 {
+		t.Fatalf("a %d character line wasn't split", utf8.RuneCountInString(line))
+	}
+	if strings.Join(parts, "") != line {
+		t.Error("parts don't join to the line")
+	}
+	for i, p := range parts {
+		if n := utf8.RuneCountInString(p); n > maxMessageLength || !utf8.ValidString(p) {
+			t.Errorf("part %d has %d characters or isn't UTF-8", i, n)
+		}
+		// unescaped, every part must close what it opens
+		unescaped := markdownV2Unescape(p)
+		if strings.HasSuffix(unescaped, `\`) || strings.Count(unescaped, "*")%2 != 0 || strings.Count(unescaped, "[") != strings.Count(unescaped, ")") {
+			t.Errorf("part %d isn't valid MarkdownV2 on its own: ...%s", i, p[max(0, len(p)-40):])
+		}
+	}
+}
+
+// markdownV2Unescape drops escaped characters from s, leaving only the markup and a
+// dangling backslash, if any.
+func markdownV2Unescape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,54 @@
+package rico
+
+import "time"
+
+// RateEvent is a detected rate change as delivered on the Updates channel.
+type RateEvent struct {
+	Old  Rate // rate compared against, zero on the first check
+	New  Rate
+	Time time.Time // when the change was detected
+}
+
+// Updates returns the channel detected rate changes are delivered on, see WithUpdates.
+// It's nil, which never delivers anything, unless WithUpdates was given. Close closes it.
+//
+// Changes are never waited for: when the buffer is full because the consumer falls
+// behind, the oldest buffered change is dropped to make room for the new one, so the
+// channel always holds the most recent changes and a slow consumer can't stall checks.
+func (rc *RateChecker) Updates() <-chan RateEvent {
+	return rc.updates
+}
+
+// publishUpdate delivers ev on the Updates channel, dropping the oldest buffered change
+// when it's full. It does nothing without the channel or once it's closed.
+func (rc *RateChecker) publishUpdate(ev RateEvent) {
+	rc.updatesMu.Lock()
+	defer rc.updatesMu.Unlock()
+	if rc.updates == nil || rc.updatesClosed {
+		return
+	}
+
+	for {
+		select {
+		case rc.updates <- ev:
+			return
+		default:
+		}
+		select {
+		case dropped := <-rc.updates:
+			rc.logger.Warn("updates consumer is behind, dropping the oldest rate change", "currency", dropped.New.Currency, "time", dropped.Time)
+		default:
+			// the consumer made room in the meantime
+		}
+	}
+}
+
+// closeUpdates closes the Updates channel once.
+func (rc *RateChecker) closeUpdates() {
+	rc.updatesMu.Lock()
+	defer rc.updatesMu.Unlock()
+	if rc.updates != nil && !rc.updatesClosed {
+		rc.updatesClosed = true
+		close(rc.updates)
+	}
+}
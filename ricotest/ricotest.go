@@ -0,0 +1,162 @@
+// Package ricotest provides test doubles for code built on package rico, so rate
+// checks can be exercised without network access or a Telegram bot.
+package ricotest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lukamindo/rico_parser_go/rico"
+)
+
+// MockNotifier is a rico.Notifier that records every event it is given.
+type MockNotifier struct {
+	mu     sync.Mutex
+	events []rico.Event
+
+	// Err, when set, is returned from every Notify call. The event is recorded anyway.
+	Err error
+}
+
+// Notify implements rico.Notifier.
+func (m *MockNotifier) Notify(ctx context.Context, ev rico.Event) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events = append(m.events, ev)
+	return m.Err
+}
+
+// Events returns the events received so far, oldest first.
+func (m *MockNotifier) Events() []rico.Event {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]rico.Event(nil), m.events...)
+}
+
+// Reset forgets the recorded events.
+func (m *MockNotifier) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events = nil
+}
+
+// Page serves a canned HTML document in place of rico.ge. Pass its Client to
+// rico.WithHTTPClient; every request, whatever its URL, gets the current document.
+type Page struct {
+	mu     sync.Mutex
+	html   string
+	status int
+}
+
+// NewPage returns a Page serving html with status 200.
+func NewPage(html string) *Page {
+	return &Page{html: html, status: http.StatusOK}
+}
+
+// Set replaces the served document, e.g. to simulate a rate change between checks.
+func (p *Page) Set(html string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.html = html
+}
+
+// SetStatus makes the page respond with the given HTTP status code.
+func (p *Page) SetStatus(code int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.status = code
+}
+
+// Client returns an HTTP client whose requests are all answered by p.
+func (p *Page) Client() *http.Client {
+	return &http.Client{Transport: p}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (p *Page) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return &http.Response{
+		Status:     fmt.Sprintf("%d %s", p.status, http.StatusText(p.status)),
+		StatusCode: p.status,
+		Header:     http.Header{"Content-Type": {"text/html; charset=utf-8"}},
+		Body:       io.NopCloser(strings.NewReader(p.html)),
+		Request:    req,
+	}, nil
+}
+
+// RatesHTML renders rates in the layout of rico.ge's rate table.
+func RatesHTML(rates ...rico.Rate) string {
+	var b strings.Builder
+	b.WriteString(`<html><body><table><tbody class="first-table-body">`)
+	for _, r := range rates {
+		fmt.Fprintf(&b, `<tr><td class="flag-title">%s</td><td class="currency-value">%.4f</td><td class="currency-value">%.4f</td></tr>`, r.Currency, r.Buy, r.Sell)
+	}
+	b.WriteString(`</tbody></table></body></html>`)
+	return b.String()
+}
+
+// Source is a rico.Source returning fixed rates, or Err when it is set.
+type Source struct {
+	SourceName string
+	Rates      map[string]rico.Rate
+	Err        error
+}
+
+// Name implements rico.Source.
+func (s *Source) Name() string {
+	return s.SourceName
+}
+
+// Fetch implements rico.Source. The returned rates are tagged with the source's name.
+func (s *Source) Fetch(ctx context.Context) (map[string]rico.Rate, error) {
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	ret := make(map[string]rico.Rate, len(s.Rates))
+	for c, r := range s.Rates {
+		r.Source = s.SourceName
+		ret[c] = r
+	}
+	return ret, nil
+}
+
+// Clock is a rico.Clock that only moves when told to.
+type Clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewClock returns a Clock showing t.
+func NewClock(t time.Time) *Clock {
+	return &Clock{now: t}
+}
+
+// Now implements rico.Clock.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Set moves the clock to t.
+func (c *Clock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+}
+
+// Advance moves the clock forward by d.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}